@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/atb-as/cleos/pkg/cleos/parquet"
+	"github.com/atb-as/cleos/pkg/cleos/s1"
+)
+
+func convertCmd(args []string) error {
+	fs := newFlagSet("convert", "file.csv")
+	format := fs.String("format", "ndjson", "output format, ndjson (one JSON object per line), json (an array of objects), csv or parquet")
+	rowGroupSize := fs.Int64("row-group-size", parquet.DefaultRowGroupSize>>20, "size of parquet row groups in MiB")
+	output := fs.String("o", "", "file to write to instead of stdout")
+	utc := fs.Bool("utc", false, "write date times in UTC instead of Europe/Oslo")
+	lenient := fs.Bool("lenient", false, "skip rows that can not be parsed and report them on stderr")
+	encodingName := encodingFlag(fs)
+	args = parseArgs(fs, args, 1)
+
+	decoding, err := reportEncoding(*encodingName)
+	if err != nil {
+		return err
+	}
+
+	enc, ok := encoders[*format]
+	if !ok && *format != "parquet" {
+		return fmt.Errorf("unknown format %q, valid formats are ndjson, json, csv and parquet", *format)
+	}
+
+	file, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Values are formatted by the type of their column rather than by the
+	// type inferred from each value, and the schema of a parquet file
+	// precedes the rows, so types are inferred from the whole report first.
+	profile := s1.NewReader(file)
+	profile.Lenient = *lenient
+	profile.Encoding = decoding
+	columns, err := s1.Profile(profile, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if *format == "parquet" {
+		enc = parquetEncoder(columns, *rowGroupSize<<20)
+	}
+
+	var out io.WriteCloser = os.Stdout
+	if *output != "" {
+		if out, err = os.Create(*output); err != nil {
+			return err
+		}
+		defer out.Close()
+	}
+
+	reader := s1.NewReader(file)
+	reader.UTC = *utc
+	reader.Lenient = *lenient
+	reader.Encoding = decoding
+	if err := convert(out, reader, columns, enc); err != nil {
+		return err
+	}
+	if errs := reader.Errors(); len(errs) > 0 {
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
+		fmt.Fprintf(os.Stderr, "skipped %d rows\n", len(errs))
+	}
+	if *output != "" {
+		return out.Close()
+	}
+	return nil
+}
+
+// A rowEncoder writes converted rows in some format.
+type rowEncoder interface {
+	// header is called once with the columns of the rows before any rows.
+	header(hdr []string, types []s1.Type) error
+	row(row []interface{}) error
+	// close is called after the last row.
+	close() error
+}
+
+var encoders = map[string]func(w *bufio.Writer) rowEncoder{
+	"ndjson": func(w *bufio.Writer) rowEncoder { return &jsonEncoder{w: w} },
+	"json":   func(w *bufio.Writer) rowEncoder { return &jsonEncoder{w: w, array: true} },
+	"csv":    func(w *bufio.Writer) rowEncoder { return &csvEncoder{w: csv.NewWriter(w)} },
+}
+
+// convert converts the report read from r, with columns as profiled by
+// s1.Profile, with the encoder made by enc, and writes the result to w one row
+// at a time. Values are decoded by the type of their column, so that for
+// example codes with leading zeros in String columns are kept as they are.
+func convert(w io.Writer, r *s1.Reader, columns []s1.Column, enc func(w *bufio.Writer) rowEncoder) error {
+	bw := bufio.NewWriter(w)
+	e := enc(bw)
+
+	r.Spec = s1.ProfileSpec(columns)
+	hdr, err := r.Header()
+	if err != nil {
+		return err
+	}
+	if len(hdr) != len(columns) {
+		return fmt.Errorf("got %d columns, profiled %d", len(hdr), len(columns))
+	}
+	types := make([]s1.Type, len(hdr))
+	for i, col := range columns {
+		types[i] = col.Type
+	}
+	if err := e.header(hdr, types); err != nil {
+		return err
+	}
+
+	for {
+		row, err := r.Row()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := e.row(row); err != nil {
+			return fmt.Errorf("line %d: %v", r.Line(), err)
+		}
+	}
+
+	if err := e.close(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// isoValue formats v, a value of a column of type t, with dates and times in
+// ISO 8601 and numbers with dot decimals. It reports whether v is text rather
+// than a number. Times are dates only in columns of TypeDate.
+func isoValue(t s1.Type, v interface{}) (s string, text bool, err error) {
+	switch v := v.(type) {
+	case string:
+		return v, true, nil
+	case s1.Amount:
+		return v.String(), false, nil
+	case int:
+		return strconv.Itoa(v), false, nil
+	case int64:
+		return strconv.FormatInt(v, 10), false, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), false, nil
+	case time.Time:
+		switch t {
+		case s1.TypeTimestamp:
+			return v.Format(time.RFC3339Nano), true, nil
+		case s1.TypeDate:
+			return v.Format("2006-01-02"), true, nil
+		default:
+			return v.Format(time.RFC3339), true, nil
+		}
+	default:
+		return "", false, fmt.Errorf("unsupported type %T", v)
+	}
+}
+
+func isNull(v interface{}) bool {
+	switch v.(type) {
+	case nil, s1.Null:
+		return true
+	default:
+		return false
+	}
+}
+
+// jsonEncoder writes rows as JSON objects with the columns in the order of
+// the header, either one per line or as the elements of an array.
+type jsonEncoder struct {
+	w     *bufio.Writer
+	array bool
+	keys  [][]byte
+	types []s1.Type
+	rows  int
+}
+
+func (e *jsonEncoder) header(hdr []string, types []s1.Type) error {
+	e.keys = make([][]byte, len(hdr))
+	for i, name := range hdr {
+		key, err := json.Marshal(name)
+		if err != nil {
+			return err
+		}
+		e.keys[i] = key
+	}
+	e.types = types
+	if e.array {
+		_, err := e.w.WriteString("[")
+		return err
+	}
+	return nil
+}
+
+func (e *jsonEncoder) row(row []interface{}) error {
+	switch {
+	case e.array && e.rows > 0:
+		e.w.WriteString(",\n")
+	case e.array:
+		e.w.WriteString("\n")
+	}
+	e.rows++
+
+	e.w.WriteByte('{')
+	for i, v := range row {
+		if i > 0 {
+			e.w.WriteByte(',')
+		}
+		e.w.Write(e.keys[i])
+		e.w.WriteByte(':')
+		if isNull(v) {
+			e.w.WriteString("null")
+			continue
+		}
+		s, text, err := isoValue(e.types[i], v)
+		if err != nil {
+			return fmt.Errorf("column %s: %v", e.keys[i], err)
+		}
+		if text {
+			b, err := json.Marshal(s)
+			if err != nil {
+				return err
+			}
+			e.w.Write(b)
+		} else {
+			e.w.WriteString(s)
+		}
+	}
+	e.w.WriteByte('}')
+	if e.array {
+		return nil
+	}
+	return e.w.WriteByte('\n')
+}
+
+func (e *jsonEncoder) close() error {
+	if !e.array {
+		return nil
+	}
+	if e.rows > 0 {
+		e.w.WriteString("\n")
+	}
+	_, err := e.w.WriteString("]\n")
+	return err
+}
+
+// csvEncoder writes rows as comma separated values, with a header.
+type csvEncoder struct {
+	w      *csv.Writer
+	hdr    []string
+	types  []s1.Type
+	record []string
+}
+
+func (e *csvEncoder) header(hdr []string, types []s1.Type) error {
+	e.hdr, e.types = hdr, types
+	e.record = make([]string, len(hdr))
+	return e.w.Write(hdr)
+}
+
+func (e *csvEncoder) row(row []interface{}) error {
+	for i, v := range row {
+		if isNull(v) {
+			e.record[i] = ""
+			continue
+		}
+		s, _, err := isoValue(e.types[i], v)
+		if err != nil {
+			return fmt.Errorf("column %s: %v", e.hdr[i], err)
+		}
+		e.record[i] = s
+	}
+	return e.w.Write(e.record)
+}
+
+func (e *csvEncoder) close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// parquetEncoder returns a function making encoders writing rows with
+// columns as a parquet file.
+func parquetEncoder(columns []s1.Column, rowGroupSize int64) func(w *bufio.Writer) rowEncoder {
+	return func(w *bufio.Writer) rowEncoder {
+		return &parquetRowEncoder{w: w, columns: columns, rowGroupSize: rowGroupSize}
+	}
+}
+
+type parquetRowEncoder struct {
+	w            *bufio.Writer
+	columns      []s1.Column
+	rowGroupSize int64
+	pw           *parquet.Writer
+}
+
+func (e *parquetRowEncoder) header(hdr []string, types []s1.Type) error {
+	pw, err := parquet.NewWriter(e.w, e.columns)
+	if err != nil {
+		return err
+	}
+	pw.RowGroupSize = e.rowGroupSize
+	e.pw = pw
+	return nil
+}
+
+func (e *parquetRowEncoder) row(row []interface{}) error {
+	return e.pw.Write(row)
+}
+
+func (e *parquetRowEncoder) close() error {
+	return e.pw.Close()
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/atb-as/cleos/pkg/cleos/s1"
+)
+
+const convertReport = `SalesDate;SalesDateTime;OrderId;Quantity;AmountInclVat;VatRate;Note;Shipped
+01.03.2020;29.03.2020 12:30:00;"A ""1""";2;-149.00;12.5;;02.03.2020
+01.03.2020;01.03.2020 00:00:00;"A2";1;0.50;0.0;"ok";
+`
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"ndjson", `{"SalesDate":"2020-03-01","SalesDateTime":"2020-03-29T12:30:00+02:00","OrderId":"A \"1\"","Quantity":2,"AmountInclVat":-149.00,"VatRate":12.5,"Note":"","Shipped":"2020-03-02"}
+{"SalesDate":"2020-03-01","SalesDateTime":"2020-03-01T00:00:00+01:00","OrderId":"A2","Quantity":1,"AmountInclVat":0.50,"VatRate":0,"Note":"ok","Shipped":null}
+`},
+		{"json", `[
+{"SalesDate":"2020-03-01","SalesDateTime":"2020-03-29T12:30:00+02:00","OrderId":"A \"1\"","Quantity":2,"AmountInclVat":-149.00,"VatRate":12.5,"Note":"","Shipped":"2020-03-02"},
+{"SalesDate":"2020-03-01","SalesDateTime":"2020-03-01T00:00:00+01:00","OrderId":"A2","Quantity":1,"AmountInclVat":0.50,"VatRate":0,"Note":"ok","Shipped":null}
+]
+`},
+		{"csv", `SalesDate,SalesDateTime,OrderId,Quantity,AmountInclVat,VatRate,Note,Shipped
+2020-03-01,2020-03-29T12:30:00+02:00,"A ""1""",2,-149.00,12.5,,2020-03-02
+2020-03-01,2020-03-01T00:00:00+01:00,A2,1,0.50,0,ok,
+`},
+	}
+
+	for _, tt := range tests {
+		var b strings.Builder
+		if err := convert(&b, s1.NewReader(strings.NewReader(convertReport)), profile(t, convertReport), encoders[tt.format]); err != nil {
+			t.Fatalf("%s: err=%v", tt.format, err)
+		}
+		if got := b.String(); got != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.format, got, tt.want)
+		}
+	}
+}
+
+// profile returns the columns of report as profiled by s1.Profile.
+func profile(t *testing.T, report string) []s1.Column {
+	t.Helper()
+	columns, err := s1.Profile(s1.NewReader(strings.NewReader(report)), 0)
+	if err != nil {
+		t.Fatalf("profile: err=%v", err)
+	}
+	return columns
+}
+
+func TestConvertColumnTypes(t *testing.T) {
+	// Shipped is not in the Spec, and its values at midnight are still
+	// date times.
+	const report = `OrderId;Shipped
+"A1";22.09.2020 00:00:00
+"A2";22.09.2020 10:30:00
+`
+	var b strings.Builder
+	if err := convert(&b, s1.NewReader(strings.NewReader(report)), profile(t, report), encoders["csv"]); err != nil {
+		t.Fatalf("err=%v", err)
+	}
+	want := `OrderId,Shipped
+A1,2020-09-22T00:00:00+02:00
+A2,2020-09-22T10:30:00+02:00
+`
+	if got := b.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestConvertStrings(t *testing.T) {
+	// Code and Note are not in the Spec, and hold strings that look like an
+	// integer and a date.
+	const report = `OrderId;Code;Note
+"A1";"0042";"22.09.2020"
+"A2";"abc";"ok"
+`
+	var b strings.Builder
+	if err := convert(&b, s1.NewReader(strings.NewReader(report)), profile(t, report), encoders["ndjson"]); err != nil {
+		t.Fatalf("err=%v", err)
+	}
+	want := `{"OrderId":"A1","Code":"0042","Note":"22.09.2020"}
+{"OrderId":"A2","Code":"abc","Note":"ok"}
+`
+	if got := b.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestConvertEmpty(t *testing.T) {
+	var b strings.Builder
+	const report = "OrderId;Quantity\n"
+	if err := convert(&b, s1.NewReader(strings.NewReader(report)), profile(t, report), encoders["json"]); err != nil {
+		t.Fatalf("err=%v", err)
+	}
+	if got, want := b.String(), "[]\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestConvertCmdLenient(t *testing.T) {
+	dir, err := ioutil.TempDir("", "csvutil")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "report.csv")
+	const report = "OrderId;Quantity\n\"A1\";1\n\"A2\"\n\"A3\";3\n"
+	if err := ioutil.WriteFile(name, []byte(report), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []string{"ndjson", "parquet"} {
+		out := filepath.Join(dir, "report."+format)
+		if err := convertCmd([]string{"-lenient", "-format", format, "-o", out, name}); err != nil {
+			t.Errorf("%s: err=%v", format, err)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/atb-as/cleos/pkg/cleos/postgres"
+)
+
+// dbConfig configures the database connection of commands using a database.
+type dbConfig struct {
+	dsn         string
+	socket      string
+	sslMode     string
+	sslRootCert string
+	sslCert     string
+	sslKey      string
+}
+
+// dbFlags adds flags configuring the database connection to fs. The flags
+// default to the values of environment variables.
+func dbFlags(fs *flag.FlagSet) *dbConfig {
+	c := &dbConfig{}
+	fs.StringVar(&c.dsn, "dsn", os.Getenv("DATABASE_URL"),
+		"connection string or postgres:// URL, replacing the DB_* variables (env DATABASE_URL)")
+	fs.StringVar(&c.socket, "socket", os.Getenv("DB_SOCKET"),
+		"directory of a Unix socket to connect through instead of DB_HOST, e.g. /cloudsql/project:region:instance (env DB_SOCKET)")
+	fs.StringVar(&c.sslMode, "sslmode", os.Getenv("DB_SSLMODE"),
+		"disable, require, verify-ca or verify-full; defaults to require, or disable with -socket (env DB_SSLMODE)")
+	fs.StringVar(&c.sslRootCert, "sslrootcert", os.Getenv("DB_SSLROOTCERT"),
+		"file with the certificate authority of the server (env DB_SSLROOTCERT)")
+	fs.StringVar(&c.sslCert, "sslcert", os.Getenv("DB_SSLCERT"),
+		"file with the client certificate (env DB_SSLCERT)")
+	fs.StringVar(&c.sslKey, "sslkey", os.Getenv("DB_SSLKEY"),
+		"file with the private key of the client certificate (env DB_SSLKEY)")
+	return c
+}
+
+// connString returns the connection string configured by c, or by the DB_*
+// environment variables if c has no DSN. Flags override the parameters of
+// the DSN, since later parameters take precedence.
+func (c *dbConfig) connString() (string, error) {
+	var dsn string
+	params := make(map[string]string)
+	switch {
+	case strings.HasPrefix(c.dsn, "postgres://"), strings.HasPrefix(c.dsn, "postgresql://"):
+		var err error
+		if dsn, err = pq.ParseURL(c.dsn); err != nil {
+			return "", fmt.Errorf("invalid database URL: %v", err)
+		}
+	case c.dsn != "":
+		dsn = c.dsn
+	default:
+		var missing []string
+		env := func(key, name string, required bool) {
+			if v := os.Getenv(name); v != "" {
+				params[key] = v
+			} else if required {
+				missing = append(missing, name)
+			}
+		}
+		env("host", "DB_HOST", c.socket == "")
+		env("port", "DB_PORT", false)
+		env("user", "DB_USER", true)
+		env("password", "DB_PASSWORD", false)
+		env("dbname", "DB_NAME", true)
+		if len(missing) > 0 {
+			return "", fmt.Errorf("no database configured: set -dsn or DATABASE_URL, or DB_HOST (or -socket), DB_USER and DB_NAME; missing %s",
+				strings.Join(missing, ", "))
+		}
+	}
+
+	if c.socket != "" {
+		params["host"] = c.socket
+		// Connections through a socket never leave the machine.
+		params["sslmode"] = "disable"
+	}
+	for key, v := range map[string]string{
+		"sslmode":     c.sslMode,
+		"sslrootcert": c.sslRootCert,
+		"sslcert":     c.sslCert,
+		"sslkey":      c.sslKey,
+	} {
+		if v != "" {
+			params[key] = v
+		}
+	}
+	return strings.TrimSpace(dsn + " " + postgres.ConnString(params)), nil
+}
+
+// open opens the database configured by c, and checks that it can be
+// connected to.
+func (c *dbConfig) open(ctx context.Context) (*sql.DB, error) {
+	dsn, err := c.connString()
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("connect to database: %v", err)
+	}
+	return db, nil
+}
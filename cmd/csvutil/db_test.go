@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// setenv sets the environment variables in env for the duration of the test.
+func setenv(t *testing.T, env map[string]string) {
+	for key, v := range env {
+		old, ok := os.LookupEnv(key)
+		os.Setenv(key, v)
+		key := key
+		t.Cleanup(func() {
+			if ok {
+				os.Setenv(key, old)
+			} else {
+				os.Unsetenv(key)
+			}
+		})
+	}
+}
+
+func TestConnString(t *testing.T) {
+	setenv(t, map[string]string{
+		"DB_HOST":     "db.example.com",
+		"DB_PORT":     "5432",
+		"DB_USER":     "cleos",
+		"DB_PASSWORD": "it's secret",
+		"DB_NAME":     "reports",
+	})
+
+	tests := []struct {
+		name string
+		c    dbConfig
+		want string
+	}{
+		{
+			"env",
+			dbConfig{sslMode: "verify-full", sslRootCert: "/certs/ca.pem"},
+			`dbname='reports' host='db.example.com' password='it\'s secret' port='5432' sslmode='verify-full' sslrootcert='/certs/ca.pem' user='cleos'`,
+		},
+		{
+			"socket",
+			dbConfig{socket: "/cloudsql/project:region:instance"},
+			`dbname='reports' host='/cloudsql/project:region:instance' password='it\'s secret' port='5432' sslmode='disable' user='cleos'`,
+		},
+		{
+			"url",
+			dbConfig{dsn: "postgres://cleos@db.example.com/reports?sslmode=require", sslCert: "/certs/client.pem", sslKey: "/certs/client.key"},
+			`dbname=reports host=db.example.com sslmode=require user=cleos sslcert='/certs/client.pem' sslkey='/certs/client.key'`,
+		},
+		{
+			"dsn",
+			dbConfig{dsn: "host=localhost dbname=reports", sslMode: "disable"},
+			`host=localhost dbname=reports sslmode='disable'`,
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.c.connString()
+		if err != nil {
+			t.Fatalf("%s: err=%v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestConnStringMissing(t *testing.T) {
+	setenv(t, map[string]string{"DB_HOST": "", "DB_USER": "cleos", "DB_NAME": ""})
+
+	_, err := (&dbConfig{}).connString()
+	if err == nil || !strings.Contains(err.Error(), "missing DB_HOST, DB_NAME") {
+		t.Errorf("got err=%v, want missing DB_HOST, DB_NAME", err)
+	}
+	if _, err := (&dbConfig{socket: "/cloudsql/x", dsn: ""}).connString(); err == nil || !strings.HasSuffix(err.Error(), "missing DB_NAME") {
+		t.Errorf("with socket: got err=%v, want only DB_NAME missing", err)
+	}
+}
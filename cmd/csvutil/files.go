@@ -0,0 +1,124 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// encodingFlag adds a flag naming the character encoding of reports to fs.
+func encodingFlag(fs *flag.FlagSet) *string {
+	return fs.String("encoding", "auto", "character encoding of reports, auto, utf-8 or windows-1252; auto detects it from the start of each report")
+}
+
+// reportEncoding returns the encoding named name, or nil for auto, which
+// makes s1.Reader detect the encoding.
+func reportEncoding(name string) (encoding.Encoding, error) {
+	switch strings.ToLower(name) {
+	case "auto":
+		return nil, nil
+	case "utf-8", "utf8":
+		return unicode.UTF8, nil
+	case "windows-1252", "cp1252":
+		return charmap.Windows1252, nil
+	default:
+		return nil, fmt.Errorf("unknown encoding %q, valid encodings are auto, utf-8 and windows-1252", name)
+	}
+}
+
+// reportFiles expands args, which are files, directories or glob patterns,
+// into the report files they name, ordered by report ID. Directories name
+// the .csv files directly in them.
+func reportFiles(args []string) ([]string, error) {
+	var names []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	for _, arg := range args {
+		matches := []string{arg}
+		if strings.ContainsAny(arg, `*?[\`) {
+			var err error
+			if matches, err = filepath.Glob(arg); err != nil {
+				return nil, fmt.Errorf("%s: %v", arg, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("%s: no matching files", arg)
+			}
+		}
+
+		for _, name := range matches {
+			fi, err := os.Stat(name)
+			if err != nil {
+				return nil, err
+			}
+			if !fi.IsDir() {
+				add(name)
+				continue
+			}
+
+			entries, err := ioutil.ReadDir(name)
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range entries {
+				if !e.IsDir() && strings.EqualFold(filepath.Ext(e.Name()), ".csv") {
+					add(filepath.Join(name, e.Name()))
+				}
+			}
+		}
+	}
+
+	sortByReportID(names)
+	return names, nil
+}
+
+// reportIDFromFilename extracts the CLEOS report ID from the name of a file
+// stored by fetch-cleos-report, which is on the form <id>_<filename>. It
+// returns "" if the name is not on that form.
+func reportIDFromFilename(name string) string {
+	base := filepath.Base(name)
+	i := strings.Index(base, "_")
+	if i <= 0 {
+		return ""
+	}
+	if _, err := strconv.ParseUint(base[:i], 10, 64); err != nil {
+		return ""
+	}
+	return base[:i]
+}
+
+// sortByReportID sorts the report files names by their numeric report ID.
+// Files without a report ID are sorted last, by name.
+func sortByReportID(names []string) {
+	sort.SliceStable(names, func(i, j int) bool {
+		a, b := reportIDFromFilename(names[i]), reportIDFromFilename(names[j])
+		switch {
+		case a == "" || b == "":
+			if a == b {
+				return names[i] < names[j]
+			}
+			return b == ""
+		case len(a) != len(b):
+			// IDs are numbers without leading zeros.
+			return len(a) < len(b)
+		case a != b:
+			return a < b
+		default:
+			return names[i] < names[j]
+		}
+	})
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func TestReportFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "csvutil")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{
+		"100_S1-2020-03.csv",
+		"99_S1-2020-02.csv",
+		"1000_S1-2020-04.CSV",
+		"notes.txt",
+		"other.csv",
+		"sub/5_S1-2020-01.csv",
+	} {
+		name = filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := reportFiles([]string{
+		dir,
+		filepath.Join(dir, "sub", "*.csv"),
+		filepath.Join(dir, "100_S1-2020-03.csv"),
+	})
+	if err != nil {
+		t.Fatalf("err=%v", err)
+	}
+	want := []string{
+		filepath.Join(dir, "sub", "5_S1-2020-01.csv"),
+		filepath.Join(dir, "99_S1-2020-02.csv"),
+		filepath.Join(dir, "100_S1-2020-03.csv"),
+		filepath.Join(dir, "1000_S1-2020-04.CSV"),
+		filepath.Join(dir, "other.csv"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%q\nwant\n%q", got, want)
+	}
+
+	if _, err := reportFiles([]string{filepath.Join(dir, "*.json")}); err == nil {
+		t.Errorf("got no error for pattern without matches")
+	}
+}
+
+func TestReportIDFromFilename(t *testing.T) {
+	names := []struct {
+		arg  string
+		want string
+	}{
+		{"/data/1234_S1-2020-03.csv", "1234"},
+		{"1234_", "1234"},
+		{"_S1.csv", ""},
+		{"S1_2020.csv", ""},
+		{"report.csv", ""},
+	}
+
+	for _, tt := range names {
+		if got := reportIDFromFilename(tt.arg); got != tt.want {
+			t.Errorf("reportIDFromFilename(%q): got %q, want %q", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestReportEncoding(t *testing.T) {
+	for name, want := range map[string]encoding.Encoding{
+		"auto":         nil,
+		"UTF-8":        unicode.UTF8,
+		"windows-1252": charmap.Windows1252,
+	} {
+		got, err := reportEncoding(name)
+		if err != nil || got != want {
+			t.Errorf("%s: got %v, err=%v, want %v", name, got, err, want)
+		}
+	}
+	if _, err := reportEncoding("latin1"); err == nil {
+		t.Errorf("latin1: expected error")
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestHyperLogLog(t *testing.T) {
+	for _, n := range []int{0, 1, 10, 1000, 100000} {
+		var h hyperLogLog
+		for i := 0; i < n; i++ {
+			// Add every value twice, duplicates must not count.
+			h.add("A" + strconv.Itoa(i))
+			h.add("A" + strconv.Itoa(i))
+		}
+		got := float64(h.estimate())
+		if diff := math.Abs(got - float64(n)); diff > 0.03*float64(n) {
+			t.Errorf("%d distinct values: got estimate %.0f", n, got)
+		}
+	}
+}
@@ -1,101 +1,40 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"io"
 	"os"
-	"strings"
-	"text/template"
-)
-
-type csvReader interface {
-	Header() ([]string, error)
-	Row() ([]interface{}, error)
-}
-
-func insertCSV(ctx context.Context, r csvReader, tableName string) error {
-	var insertTmpl = template.Must(template.New("insertTmpl").Parse(`
-INSERT into {{ .Name }} ({{ .Columns }})
-VALUES ({{ .Placeholders }})`))
-	var (
-		user     = os.Getenv("DB_USER")
-		port     = os.Getenv("DB_PORT")
-		host     = os.Getenv("DB_HOST")
-		password = os.Getenv("DB_PASSWORD")
-		dbname   = os.Getenv("DB_NAME")
-	)
-	psqlInfo := fmt.Sprintf("host=%s port=%s user=%s "+
-		"password=%s dbname=%s sslmode=disable",
-		host, port, user, password, dbname)
 
-	db, err := sql.Open("postgres", psqlInfo)
-	if err != nil {
-		return err
-	}
-	defer db.Close()
-
-	// Grab the CSV header row. The header is used to populate the insert
-	// statement template and to generate value placeholders.
-	header, err := r.Header()
-	if err != nil {
-		return err
-	}
+	"github.com/atb-as/cleos/pkg/cleos/postgres"
+)
 
-	// Populate and execute our template.
-	var buf bytes.Buffer
-	var data = struct {
-		Name         string
-		Columns      string
-		Placeholders string
-	}{tableName, columns(header), placeholders(header)}
-	if err = insertTmpl.Execute(&buf, data); err != nil {
-		return err
-	}
-	insertStmt := buf.String()
+func insertCmd(args []string) error {
+	fs := newFlagSet("insert", "tableName file.csv|dir|glob...")
+	lf := addLoadFlags(fs)
+	dbc := dbFlags(fs)
+	args = parseArgs(fs, args, 2)
 
-	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	opts, err := lf.options()
 	if err != nil {
 		return err
 	}
-	defer func() error {
-		err = tx.Rollback()
-		return err
-	}()
-
-	for {
-		row, err := r.Row()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return err
-		}
-
-		if _, err := tx.ExecContext(ctx, insertStmt, row...); err != nil {
-			return err
-		}
-	}
-
-	if err = tx.Commit(); err != nil {
-		return err
-	}
-	return nil
+	return loadFilesCmd(dbc, args[0], args[1:], opts)
 }
 
-func placeholders(in []string) string {
-	var s strings.Builder
-	for i := range in {
-		s.WriteString(fmt.Sprintf("$%d", i+1))
-		if i != len(in)-1 {
-			s.WriteString(",")
+// insertCSV inserts the rows read from r into tableName, along with their
+// provenance, and returns the number of rows added, as postgres.Copy does.
+//
+// Rows are streamed through COPY, unless batch is set or the server does not
+// support COPY, in which case they are inserted in batches.
+func insertCSV(ctx context.Context, tx *sql.Tx, r postgres.RowReader, tableName string, prov postgres.Provenance, action postgres.ConflictAction, batch bool) (int64, error) {
+	if !batch {
+		n, err := postgres.Copy(ctx, tx, r, tableName, prov, action)
+		if !errors.Is(err, postgres.ErrCopyUnavailable) {
+			return n, err
 		}
+		fmt.Fprintf(os.Stderr, "%v, inserting in batches\n", err)
 	}
-	return s.String()
-}
-
-func columns(hdr []string) string {
-	return strings.Join(hdr, ",")
+	return postgres.InsertBatches(ctx, tx, r, tableName, prov, action)
 }
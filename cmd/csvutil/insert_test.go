@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atb-as/cleos/pkg/cleos/postgres"
+	"github.com/atb-as/cleos/pkg/cleos/s1"
+)
+
+// benchmarkReport returns an S-1 report with n rows.
+func benchmarkReport(n int) string {
+	var b strings.Builder
+	b.WriteString("AccountingDate;SalesDateTime;OrderId;ProductCode;Quantity;AmountInclVat;VatRate;Currency\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "01.03.2020;01.03.2020 12:%02d:00;\"A%d\";\"P%d\";%d;%d.50;12.0;\"NOK\"\n",
+			i%60, i, i%10, i%5+1, i%1000)
+	}
+	return b.String()
+}
+
+// benchmarkInsert benchmarks insertCSV against the database configured by
+// the DB_* environment variables. The benchmark is skipped if DB_HOST is not
+// set, and leaves no tables behind.
+func benchmarkInsert(b *testing.B, batch bool) {
+	if os.Getenv("DB_HOST") == "" {
+		b.Skip("DB_HOST not set")
+	}
+	const rows = 10000
+	report := benchmarkReport(rows)
+
+	columns, err := s1.Profile(s1.NewReader(strings.NewReader(report)), 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	schema, err := postgres.Schema(columns, "csvutil_benchmark")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	ctx := context.Background()
+	db, err := (&dbConfig{sslMode: os.Getenv("DB_SSLMODE")}).open(ctx)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer db.Close()
+
+	prov := postgres.Provenance{SourceFile: "benchmark.csv", LoadedAt: time.Now()}
+	var elapsed time.Duration
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := tx.ExecContext(ctx, schema); err != nil {
+			b.Fatal(err)
+		}
+		r := s1.NewReader(strings.NewReader(report))
+		b.StartTimer()
+
+		start := time.Now()
+		if _, err := insertCSV(ctx, tx, r, "csvutil_benchmark", prov, postgres.ConflictSkip, batch); err != nil {
+			b.Fatal(err)
+		}
+
+		elapsed += time.Since(start)
+
+		b.StopTimer()
+		tx.Rollback()
+		b.StartTimer()
+	}
+	b.ReportMetric(float64(rows*b.N)/elapsed.Seconds(), "rows/s")
+}
+
+func BenchmarkInsertCopy(b *testing.B) {
+	benchmarkInsert(b, false)
+}
+
+func BenchmarkInsertBatch(b *testing.B) {
+	benchmarkInsert(b, true)
+}
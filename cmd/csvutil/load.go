@@ -0,0 +1,434 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"golang.org/x/text/encoding"
+
+	"github.com/atb-as/cleos/pkg/cleos/postgres"
+	"github.com/atb-as/cleos/pkg/cleos/s1"
+)
+
+func loadCmd(args []string) error {
+	fs := newFlagSet("load", "tableName file.csv|dir|glob...")
+	lf := addLoadFlags(fs)
+	dbc := dbFlags(fs)
+	args = parseArgs(fs, args, 2)
+
+	opts, err := lf.options()
+	if err != nil {
+		return err
+	}
+	opts.migrate = true
+
+	return loadFilesCmd(dbc, args[0], args[1:], opts)
+}
+
+// loadFilesCmd loads the report files named by args into tableName and
+// prints a report of the outcome for each file.
+func loadFilesCmd(dbc *dbConfig, tableName string, args []string, opts loadOptions) error {
+	names, err := reportFiles(args)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	db, err := dbc.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := postgres.CreateLedger(ctx, db); err != nil {
+		return err
+	}
+
+	results := loadFiles(ctx, db, tableName, names, opts)
+	for _, res := range results {
+		res.print()
+	}
+	if len(results) > 1 {
+		printResults(results)
+	}
+
+	var failed int
+	for _, res := range results {
+		if res.failed() {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files failed to load", failed, len(results))
+	}
+	return nil
+}
+
+// loadFlags are the flags of the commands loading reports.
+type loadFlags struct {
+	lenient    *bool
+	reportID   *string
+	onConflict *string
+	force      *bool
+	batch      *bool
+	parallel   *int
+	singleTx   *bool
+	encoding   *string
+}
+
+func addLoadFlags(fs *flag.FlagSet) *loadFlags {
+	return &loadFlags{
+		lenient:    fs.Bool("lenient", false, "skip rows that can not be parsed and report them on stderr"),
+		reportID:   fs.String("report-id", "", "CLEOS report ID to record for each row, instead of the <id> of files named <id>_<filename>"),
+		onConflict: fs.String("on-conflict", "skip", "what to do with rows already loaded from a file, skip or update"),
+		force:      fs.Bool("force", false, "load files even if a file with the same checksum has been loaded before"),
+		batch:      fs.Bool("batch", false, "insert rows in batches rather than streaming them through COPY"),
+		parallel:   fs.Int("parallel", 4, "number of files to load at the same time"),
+		singleTx:   fs.Bool("single-tx", false, "load all files in a single transaction, one at a time, instead of one transaction per file"),
+		encoding:   encodingFlag(fs),
+	}
+}
+
+func (f *loadFlags) options() (loadOptions, error) {
+	action, err := conflictAction(*f.onConflict)
+	if err != nil {
+		return loadOptions{}, err
+	}
+	if *f.parallel < 1 {
+		return loadOptions{}, fmt.Errorf("-parallel must be at least 1")
+	}
+	enc, err := reportEncoding(*f.encoding)
+	if err != nil {
+		return loadOptions{}, err
+	}
+	return loadOptions{
+		lenient:  *f.lenient,
+		reportID: *f.reportID,
+		action:   action,
+		force:    *f.force,
+		batch:    *f.batch,
+		parallel: *f.parallel,
+		singleTx: *f.singleTx,
+		encoding: enc,
+	}, nil
+}
+
+func conflictAction(name string) (postgres.ConflictAction, error) {
+	switch name {
+	case "skip":
+		return postgres.ConflictSkip, nil
+	case "update":
+		return postgres.ConflictUpdate, nil
+	default:
+		return 0, fmt.Errorf("unknown conflict action %q, valid actions are skip and update", name)
+	}
+}
+
+// loadOptions control how reports are loaded.
+type loadOptions struct {
+	// lenient skips rows that can not be parsed.
+	lenient bool
+	// reportID is the CLEOS report ID recorded for each row. If empty, it is
+	// taken from the file name.
+	reportID string
+	// action is what to do with rows already loaded.
+	action postgres.ConflictAction
+	// force loads reports even if they have been loaded before.
+	force bool
+	// batch inserts rows in batches rather than streaming them through COPY.
+	batch bool
+	// migrate creates or migrates the table to hold the report before
+	// loading it, from a profile of all its rows.
+	migrate bool
+	// parallel is the maximum number of files loaded at the same time.
+	parallel int
+	// singleTx loads all files in a single transaction.
+	singleTx bool
+	// encoding is the character encoding of the reports, or nil to detect
+	// the encoding of each report.
+	encoding encoding.Encoding
+}
+
+// loadResult is the outcome of loading a report file.
+type loadResult struct {
+	name string
+	ing  *postgres.Ingestion
+	// stmts are the statements creating or migrating the table.
+	stmts []string
+	// errs are the errors of the rows skipped by a lenient reader.
+	errs []*s1.ParseError
+	err  error
+}
+
+// failed reports whether the file failed to load. Files refused for having
+// been loaded before have not failed.
+func (r *loadResult) failed() bool {
+	_, loaded := r.err.(*loadedError)
+	return r.err != nil && !loaded
+}
+
+func (r *loadResult) status() string {
+	switch {
+	case r.err == nil:
+		return "loaded"
+	case !r.failed():
+		return "already loaded"
+	case r.ing.Status == postgres.IngestionFailed:
+		return "failed"
+	default:
+		return "not loaded"
+	}
+}
+
+// print prints a summary of r, with skipped rows and errors on stderr.
+func (r *loadResult) print() {
+	for _, err := range r.errs {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", r.name, err)
+	}
+	if len(r.errs) > 0 {
+		fmt.Fprintf(os.Stderr, "%s: skipped %d rows\n", r.name, len(r.errs))
+	}
+	if r.err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", r.name, r.err)
+		return
+	}
+	for _, stmt := range r.stmts {
+		fmt.Println(stmt)
+	}
+	fmt.Printf("%s: added %d rows to %s (ingestion %d)\n", r.name, r.ing.Rows, r.ing.TableName, r.ing.ID)
+}
+
+// printResults prints a table of the outcome for each file.
+func printResults(results []*loadResult) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "FILE\tREPORT\tADDED\tSKIPPED\tSTATUS")
+	for _, res := range results {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\n", res.name, res.ing.ReportID, res.ing.Rows, len(res.errs), res.status())
+	}
+	w.Flush()
+}
+
+// loadFiles loads the report files names into tableName, in one transaction
+// per file with at most opts.parallel files at a time, or in a single
+// transaction one file at a time if opts.singleTx is set. It returns the
+// outcome for each file, in the order of names.
+func loadFiles(ctx context.Context, db *sql.DB, tableName string, names []string, opts loadOptions) []*loadResult {
+	results := make([]*loadResult, len(names))
+	for i, name := range names {
+		reportID := opts.reportID
+		if reportID == "" {
+			reportID = reportIDFromFilename(name)
+		}
+		results[i] = &loadResult{
+			name: name,
+			ing: &postgres.Ingestion{
+				ReportID:  reportID,
+				Filename:  filepath.Base(name),
+				TableName: tableName,
+			},
+		}
+	}
+
+	if opts.singleTx {
+		loadSingleTx(ctx, db, results, opts)
+		return results
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, opts.parallel)
+	for _, res := range results {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(res *loadResult) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			res.err = loadFile(ctx, db, nil, res, opts)
+		}(res)
+	}
+	wg.Wait()
+	return results
+}
+
+// loadSingleTx loads the files of results in a single transaction. If a file
+// fails to load, none of the files are loaded.
+func loadSingleTx(ctx context.Context, db *sql.DB, results []*loadResult, opts loadOptions) {
+	err := func() error {
+		tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		for _, res := range results {
+			res.err = loadFile(ctx, db, tx, res, opts)
+			if res.failed() {
+				return res.err
+			}
+		}
+		return tx.Commit()
+	}()
+	if err == nil {
+		return
+	}
+
+	for _, res := range results {
+		if res.err == nil {
+			res.ing.Rows = 0
+			res.err = fmt.Errorf("not loaded: %v", err)
+		} else if res.failed() {
+			res.err = recordFailure(ctx, db, res.ing, res.err)
+		}
+	}
+}
+
+// loadFile loads the report file of res into res.ing.TableName, and records
+// the ingestion in the ledger. If tx is nil, the file is loaded in a
+// transaction of its own, and a failed ingestion is recorded after rolling
+// it back. Otherwise the file is loaded in tx, and failures are left for the
+// caller to record once tx is rolled back.
+func loadFile(ctx context.Context, db *sql.DB, tx *sql.Tx, res *loadResult, opts loadOptions) error {
+	res.ing.StartedAt = time.Now()
+	err := loadFileTx(ctx, db, tx, res, opts)
+	if err == nil || tx != nil {
+		return err
+	}
+	if _, ok := err.(*loadedError); ok {
+		return err
+	}
+	return recordFailure(ctx, db, res.ing, err)
+}
+
+// recordFailure records ing as failed with err in the ledger, and returns err.
+func recordFailure(ctx context.Context, db *sql.DB, ing *postgres.Ingestion, err error) error {
+	ing.Rows = 0
+	ing.Status = postgres.IngestionFailed
+	ing.Error = err.Error()
+	ing.FinishedAt = time.Now()
+	if rerr := postgres.RecordIngestion(ctx, db, ing); rerr != nil {
+		return fmt.Errorf("%v (recording the failed ingestion: %v)", err, rerr)
+	}
+	return err
+}
+
+func loadFileTx(ctx context.Context, db *sql.DB, tx *sql.Tx, res *loadResult, opts loadOptions) error {
+	file, err := os.Open(res.name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	ing := res.ing
+	if ing.Checksum, err = fileChecksum(file); err != nil {
+		return err
+	}
+
+	var columns []s1.Column
+	if opts.migrate {
+		profile := s1.NewReader(file)
+		profile.Lenient = opts.lenient
+		profile.Encoding = opts.encoding
+		if columns, err = s1.Profile(profile, 0); err != nil {
+			return fmt.Errorf("profile: %v", err)
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+	}
+
+	reader := s1.NewReader(file)
+	reader.Lenient = opts.lenient
+	reader.Encoding = opts.encoding
+	if columns != nil {
+		// Decode values by the type of their column, so that for example
+		// codes with leading zeros in String columns are kept as they are.
+		reader.Spec = s1.ProfileSpec(columns)
+	}
+	defer func() {
+		res.errs = reader.Errors()
+	}()
+
+	if tx == nil {
+		if tx, err = db.BeginTx(ctx, &sql.TxOptions{}); err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		if err := loadReport(ctx, tx, reader, res, columns, opts); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+	return loadReport(ctx, tx, reader, res, columns, opts)
+}
+
+// fileChecksum returns the hex encoded SHA-256 checksum of the contents of
+// f, and rewinds f.
+func fileChecksum(f *os.File) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// loadedError is returned when loading a report file that has already been
+// loaded.
+type loadedError struct {
+	prev *postgres.Ingestion
+}
+
+func (e *loadedError) Error() string {
+	return fmt.Sprintf("%s with checksum %s was already loaded into %s at %s (ingestion %d), use -force to load it again",
+		e.prev.Filename, e.prev.Checksum, e.prev.TableName, e.prev.FinishedAt.Format(time.RFC3339), e.prev.ID)
+}
+
+// loadReport loads the report read from r into res.ing.TableName in tx, and
+// records the successful ingestion in the ledger. If opts.migrate is set, the
+// table is first created or migrated to hold columns.
+//
+// Unless opts.force is set, a report file with the same checksum as one
+// already loaded is refused, leaving tx untouched.
+func loadReport(ctx context.Context, tx *sql.Tx, r postgres.RowReader, res *loadResult, columns []s1.Column, opts loadOptions) error {
+	ing := res.ing
+	prev, err := postgres.LastIngestion(ctx, tx, ing.Checksum)
+	if err != nil {
+		return err
+	}
+	if prev != nil && !opts.force {
+		return &loadedError{prev}
+	}
+
+	if opts.migrate {
+		if res.stmts, err = postgres.Migrate(ctx, tx, ing.TableName, columns); err != nil {
+			return err
+		}
+	}
+
+	prov := postgres.Provenance{
+		SourceFile: ing.Filename,
+		ReportID:   ing.ReportID,
+		LoadedAt:   ing.StartedAt,
+	}
+	if ing.Rows, err = insertCSV(ctx, tx, r, ing.TableName, prov, opts.action, opts.batch); err != nil {
+		return err
+	}
+
+	ing.Status = postgres.IngestionSucceeded
+	ing.FinishedAt = time.Now()
+	return postgres.RecordIngestion(ctx, tx, ing)
+}
+
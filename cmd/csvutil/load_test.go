@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atb-as/cleos/pkg/cleos/postgres"
+	"github.com/atb-as/cleos/pkg/cleos/s1"
+)
+
+func TestLoadResultStatus(t *testing.T) {
+	prev := &postgres.Ingestion{ID: 1, Filename: "1_report.csv", Checksum: "abc", TableName: "sales"}
+	tests := []struct {
+		err    error
+		status postgres.IngestionStatus
+		failed bool
+		want   string
+	}{
+		{nil, postgres.IngestionSucceeded, false, "loaded"},
+		{&loadedError{prev}, "", false, "already loaded"},
+		{errors.New("bad row"), postgres.IngestionFailed, true, "failed"},
+		{errors.New("not loaded: bad row"), "", true, "not loaded"},
+	}
+
+	for _, tt := range tests {
+		res := &loadResult{ing: &postgres.Ingestion{Status: tt.status}, err: tt.err}
+		if got := res.failed(); got != tt.failed {
+			t.Errorf("%v: got failed %v, want %v", tt.err, got, tt.failed)
+		}
+		if got := res.status(); got != tt.want {
+			t.Errorf("%v: got status %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+// TestLoadReport loads a report into a new table in the database configured
+// by the DB_* environment variables, then loads it again with and without
+// force. The test is skipped if DB_HOST is not set, and runs in a
+// transaction that is rolled back.
+func TestLoadReport(t *testing.T) {
+	if os.Getenv("DB_HOST") == "" {
+		t.Skip("DB_HOST not set")
+	}
+	const report = "OrderId;Quantity\n\"A1\";1\n\"A2\";2\n"
+	columns := profile(t, report)
+
+	ctx := context.Background()
+	db, err := (&dbConfig{sslMode: os.Getenv("DB_SSLMODE")}).open(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if err := postgres.CreateLedger(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+
+	suffix := time.Now().UnixNano()
+	load := func(force bool) (*loadResult, error) {
+		res := &loadResult{
+			name: "1_report.csv",
+			ing: &postgres.Ingestion{
+				ReportID:  "1",
+				Filename:  "1_report.csv",
+				Checksum:  fmt.Sprintf("test-%d", suffix),
+				TableName: fmt.Sprintf("csvutil_test_%d", suffix),
+				StartedAt: time.Now(),
+			},
+		}
+		opts := loadOptions{action: postgres.ConflictSkip, force: force, migrate: true}
+		err := loadReport(ctx, tx, s1.NewReader(strings.NewReader(report)), res, columns, opts)
+		return res, err
+	}
+
+	first, err := load(false)
+	if err != nil {
+		t.Fatalf("first load: err=%v", err)
+	}
+	if first.ing.Rows != 2 || first.ing.ID == 0 || len(first.stmts) != 1 {
+		t.Errorf("first load: got %d rows, ingestion %d and statements %q, want 2 rows and the table created",
+			first.ing.Rows, first.ing.ID, first.stmts)
+	}
+
+	_, err = load(false)
+	if lerr, ok := err.(*loadedError); !ok || lerr.prev.ID != first.ing.ID {
+		t.Errorf("second load: got err=%v, want refusal citing ingestion %d", err, first.ing.ID)
+	}
+
+	forced, err := load(true)
+	if err != nil {
+		t.Fatalf("forced load: err=%v", err)
+	}
+	if forced.ing.Rows != 0 || forced.ing.ID == first.ing.ID {
+		t.Errorf("forced load: got %d rows added in ingestion %d, want 0 rows in a new ingestion", forced.ing.Rows, forced.ing.ID)
+	}
+
+	prev, err := postgres.LastIngestion(ctx, tx, first.ing.Checksum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prev == nil || prev.ID != forced.ing.ID || prev.Rows != 0 || prev.Status != postgres.IngestionSucceeded {
+		t.Errorf("got last ingestion %+v, want the forced ingestion %d", prev, forced.ing.ID)
+	}
+}
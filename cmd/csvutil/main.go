@@ -1,14 +1,11 @@
 package main
 
 import (
-	"context"
+	"flag"
 	"fmt"
 	"os"
 
 	_ "github.com/lib/pq"
-
-	"github.com/atb-as/cleos/pkg/cleos/postgres"
-	"github.com/atb-as/cleos/pkg/cleos/s1"
 )
 
 func main() {
@@ -22,50 +19,22 @@ func run() error {
 	if len(os.Args) < 2 {
 		printUsage()
 	}
+	args := os.Args[2:]
 	switch os.Args[1] {
 	case "schema":
-		args := os.Args[2:]
-		if len(args) < 2 {
-			fmt.Fprintf(os.Stderr, "usage: %s %s tableName file.csv\n", os.Args[0], os.Args[1])
-			os.Exit(1)
-		}
-
-		file, err := os.Open(args[1])
-		if err != nil {
-			return err
-		}
-
-		s1reader := s1.NewReader(file)
-
-		hdr, err := s1reader.Header()
-		if err != nil {
-			return err
-		}
-
-		row1, err := s1reader.Row()
-		if err != nil {
-			return err
-		}
-
-		s, err := postgres.Schema(hdr, row1, args[0])
-		if err != nil {
-			return err
-		}
-		fmt.Println(s)
+		return schemaCmd(args)
+	case "stats":
+		return statsCmd(args)
+	case "validate":
+		return validateCmd(args)
+	case "convert":
+		return convertCmd(args)
 	case "insert":
-		args := os.Args[2:]
-		if len(args) < 2 {
-			fmt.Fprintf(os.Stderr, "usage: %s %s tableName file.csv", os.Args[0], os.Args[1])
-			os.Exit(1)
-		}
-		file, err := os.Open(args[1])
-		if err != nil {
-			return err
-		}
-
-		reader := s1.NewReader(file)
-
-		return insertCSV(context.Background(), reader, args[0])
+		return insertCmd(args)
+	case "load":
+		return loadCmd(args)
+	case "migrate":
+		return migrateCmd(args)
 	default:
 		printUsage()
 	}
@@ -75,7 +44,34 @@ func run() error {
 func printUsage() {
 	fmt.Println(`invalid command, valid commands are:
 
-	insert	insert values from csv file
-	schema	generate sql schema from csv headers`)
+	convert		convert csv file to json lines, json, normalised csv or parquet
+	insert		insert values from csv file into an existing table
+	load		create or migrate a table and insert values from csv file
+	migrate		generate sql migrating a table to hold a csv file
+	schema		generate postgres or bigquery schema from csv file
+	stats		print statistics of the columns of csv file
+	validate	check csv file and print a json report`)
 	os.Exit(1)
 }
+
+// newFlagSet returns a flag set for the command name, which takes the
+// positional arguments described by usage.
+func newFlagSet(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s %s [flags] %s\navailable flags:\n", os.Args[0], name, usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseArgs parses args with fs, and exits with a usage message unless at
+// least n positional arguments remain.
+func parseArgs(fs *flag.FlagSet, args []string, n int) []string {
+	fs.Parse(args)
+	if fs.NArg() < n {
+		fs.Usage()
+		os.Exit(1)
+	}
+	return fs.Args()
+}
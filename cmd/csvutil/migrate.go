@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/atb-as/cleos/pkg/cleos/postgres"
+)
+
+func migrateCmd(args []string) error {
+	fs := newFlagSet("migrate", "tableName file.csv")
+	apply := fs.Bool("apply", false, "apply the statements instead of printing them")
+	sample := fs.Int("sample", 0, "number of rows to infer the schema from, 0 for the whole file")
+	encodingName := encodingFlag(fs)
+	dbc := dbFlags(fs)
+	args = parseArgs(fs, args, 2)
+
+	enc, err := reportEncoding(*encodingName)
+	if err != nil {
+		return err
+	}
+	columns, err := profileFile(args[1], *sample, enc)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	db, err := dbc.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	stmts, err := postgres.TableMigration(ctx, db, args[0], columns)
+	if err != nil {
+		return err
+	}
+
+	if !*apply {
+		for _, stmt := range stmts {
+			fmt.Println(stmt)
+		}
+		return nil
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("%s: %v", stmt, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	fmt.Printf("applied %d statements\n", len(stmts))
+	return nil
+}
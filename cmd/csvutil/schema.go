@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/text/encoding"
+
+	"github.com/atb-as/cleos/pkg/cleos/bigquery"
+	"github.com/atb-as/cleos/pkg/cleos/postgres"
+	"github.com/atb-as/cleos/pkg/cleos/s1"
+)
+
+func schemaCmd(args []string) error {
+	fs := newFlagSet("schema", "tableName file.csv")
+	sample := fs.Int("sample", 0, "number of rows to infer the schema from, 0 for the whole file")
+	dialect := fs.String("dialect", "postgres", "sql dialect of the schema, postgres or bigquery")
+	jsonSchema := fs.Bool("json", false, "generate a BigQuery JSON schema instead of sql, implies -dialect=bigquery")
+	encodingName := encodingFlag(fs)
+	args = parseArgs(fs, args, 2)
+
+	enc, err := reportEncoding(*encodingName)
+	if err != nil {
+		return err
+	}
+	columns, err := profileFile(args[1], *sample, enc)
+	if err != nil {
+		return err
+	}
+
+	if *jsonSchema {
+		b, err := bigquery.JSONSchema(columns)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+
+	var s string
+	switch *dialect {
+	case "postgres":
+		s, err = postgres.Schema(columns, args[0])
+	case "bigquery":
+		s, err = bigquery.Schema(columns, args[0])
+	default:
+		return fmt.Errorf("unknown dialect %q, valid dialects are postgres and bigquery", *dialect)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println(s)
+	return nil
+}
+
+// profileFile returns the profile of the first sample rows of the S-1 report
+// in the file name, or of all rows if sample is zero. The report is decoded
+// from enc, or from the encoding detected if enc is nil.
+func profileFile(name string, sample int, enc encoding.Encoding) ([]s1.Column, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	r := s1.NewReader(file)
+	r.Encoding = enc
+	return s1.Profile(r, sample)
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/atb-as/cleos/pkg/cleos/s1"
+)
+
+func statsCmd(args []string) error {
+	fs := newFlagSet("stats", "file.csv")
+	jsonOutput := fs.Bool("json", false, "print the statistics as JSON instead of a table")
+	lenient := fs.Bool("lenient", false, "skip rows that can not be parsed and report them on stderr")
+	encodingName := encodingFlag(fs)
+	args = parseArgs(fs, args, 1)
+
+	enc, err := reportEncoding(*encodingName)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Types are inferred from the whole report, so it is read twice.
+	profile := s1.NewReader(file)
+	profile.Lenient = *lenient
+	profile.Encoding = enc
+	columns, err := s1.Profile(profile, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	reader := s1.NewReader(file)
+	reader.Lenient = *lenient
+	reader.Encoding = enc
+	st, err := stats(reader, columns)
+	if err != nil {
+		return err
+	}
+	if errs := reader.Errors(); len(errs) > 0 {
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
+		fmt.Fprintf(os.Stderr, "skipped %d rows\n", len(errs))
+	}
+
+	if *jsonOutput {
+		b, err := json.MarshalIndent(st, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+	return st.print(os.Stdout)
+}
+
+// reportStats are the statistics of an S-1 report.
+type reportStats struct {
+	Rows    int            `json:"rows"`
+	Columns []*columnStats `json:"columns"`
+}
+
+// columnStats are the statistics of a column. Min and Max are formatted like
+// by csvutil convert, with dates in ISO 8601 and dot decimals.
+type columnStats struct {
+	Name     string  `json:"name"`
+	Type     string  `json:"type"`
+	Nulls    int     `json:"nulls"`
+	Min      *string `json:"min"`
+	Max      *string `json:"max"`
+	Distinct uint64  `json:"distinct"`
+	// Sum is the sum of the values of amount columns.
+	Sum *string `json:"sum,omitempty"`
+
+	typ      s1.Type
+	min, max interface{}
+	sum      s1.Amount
+	hll      hyperLogLog
+}
+
+// stats computes the statistics of the report read from r, with columns as
+// profiled by s1.Profile. Values are decoded by the type of their column.
+func stats(r *s1.Reader, columns []s1.Column) (*reportStats, error) {
+	r.Spec = s1.ProfileSpec(columns)
+	hdr, err := r.Header()
+	if err != nil {
+		return nil, err
+	}
+	if len(hdr) != len(columns) {
+		return nil, fmt.Errorf("got %d columns, profiled %d", len(hdr), len(columns))
+	}
+
+	st := &reportStats{Columns: make([]*columnStats, len(columns))}
+	for i, col := range columns {
+		st.Columns[i] = &columnStats{Name: col.Name, Type: col.Type.String(), typ: col.Type}
+	}
+
+	for {
+		row, err := r.Row()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		st.Rows++
+		for i, v := range row {
+			if err := st.Columns[i].observe(v); err != nil {
+				return nil, fmt.Errorf("line %d, column %s: %v", r.Line(), hdr[i], err)
+			}
+		}
+	}
+
+	for _, c := range st.Columns {
+		if err := c.finish(); err != nil {
+			return nil, fmt.Errorf("column %s: %v", c.Name, err)
+		}
+	}
+	return st, nil
+}
+
+func (c *columnStats) observe(v interface{}) error {
+	if isNull(v) || v == "" {
+		c.Nulls++
+		return nil
+	}
+
+	s, _, err := isoValue(c.typ, v)
+	if err != nil {
+		return err
+	}
+	c.hll.add(s)
+
+	if a, ok := v.(s1.Amount); ok {
+		c.sum = c.sum.Add(a)
+	}
+	if c.min == nil || less(c.typ, v, c.min) {
+		c.min = v
+	}
+	if c.max == nil || less(c.typ, c.max, v) {
+		c.max = v
+	}
+	return nil
+}
+
+// finish formats the statistics gathered by observe.
+func (c *columnStats) finish() error {
+	c.Distinct = c.hll.estimate()
+	for _, m := range []struct {
+		v   interface{}
+		dst **string
+	}{{c.min, &c.Min}, {c.max, &c.Max}} {
+		if m.v == nil {
+			continue
+		}
+		s, _, err := isoValue(c.typ, m.v)
+		if err != nil {
+			return err
+		}
+		*m.dst = &s
+	}
+	if c.typ == s1.TypeAmount {
+		s := c.sum.String()
+		c.Sum = &s
+	}
+	return nil
+}
+
+// less reports whether a is less than b, values of a column of type t. Values
+// of columns of strings, which may hold values decoded as other types, are
+// compared as text.
+func less(t s1.Type, a, b interface{}) bool {
+	if t != s1.TypeString && t != s1.TypeUnknown {
+		switch a := a.(type) {
+		case time.Time:
+			if b, ok := b.(time.Time); ok {
+				return a.Before(b)
+			}
+		case s1.Amount:
+			if b, ok := b.(s1.Amount); ok {
+				return a < b
+			}
+		}
+		if x, ok := number(a); ok {
+			if y, ok := number(b); ok {
+				return x < y
+			}
+		}
+	}
+	x, _, _ := isoValue(t, a)
+	y, _, _ := isoValue(t, b)
+	return x < y
+}
+
+func number(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case s1.Amount:
+		return v.Float64(), true
+	default:
+		return 0, false
+	}
+}
+
+// print prints st as a table.
+func (st *reportStats) print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "COLUMN\tTYPE\tNULLS\tMIN\tMAX\tDISTINCT\tSUM\n")
+	for _, c := range st.Columns {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\t~%d\t%s\n",
+			c.Name, c.Type, c.Nulls, orEmpty(c.Min), orEmpty(c.Max), c.Distinct, orEmpty(c.Sum))
+	}
+	fmt.Fprintf(tw, "%d rows\n", st.Rows)
+	return tw.Flush()
+}
+
+// orEmpty returns *s with tabs and newlines replaced, or "" if s is nil.
+func orEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return strings.NewReplacer("\t", " ", "\n", " ").Replace(*s)
+}
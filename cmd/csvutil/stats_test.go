@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/atb-as/cleos/pkg/cleos/s1"
+)
+
+func TestStats(t *testing.T) {
+	columns, err := s1.Profile(s1.NewReader(strings.NewReader(convertReport)), 0)
+	if err != nil {
+		t.Fatalf("profile: err=%v", err)
+	}
+	st, err := stats(s1.NewReader(strings.NewReader(convertReport)), columns)
+	if err != nil {
+		t.Fatalf("err=%v", err)
+	}
+
+	if st.Rows != 2 {
+		t.Errorf("got %d rows, want 2", st.Rows)
+	}
+
+	var got []string
+	for _, c := range st.Columns {
+		got = append(got, fmt.Sprintf("%s %s nulls=%d min=%s max=%s distinct=%d sum=%s",
+			c.Name, c.Type, c.Nulls, orEmpty(c.Min), orEmpty(c.Max), c.Distinct, orEmpty(c.Sum)))
+	}
+	want := []string{
+		"SalesDate Date nulls=0 min=2020-03-01 max=2020-03-01 distinct=1 sum=",
+		"SalesDateTime DateTime nulls=0 min=2020-03-01T00:00:00+01:00 max=2020-03-29T12:30:00+02:00 distinct=2 sum=",
+		`OrderId String nulls=0 min=A "1" max=A2 distinct=2 sum=`,
+		"Quantity Integer nulls=0 min=1 max=2 distinct=2 sum=",
+		"AmountInclVat Amount nulls=0 min=-149.00 max=0.50 distinct=2 sum=-148.50",
+		"VatRate Decimal nulls=0 min=0 max=12.5 distinct=2 sum=",
+		"Note String nulls=1 min=ok max=ok distinct=1 sum=",
+		"Shipped Date nulls=1 min=2020-03-02 max=2020-03-02 distinct=1 sum=",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestStatsStrings(t *testing.T) {
+	// Code is not in the Spec, and its values are compared as strings even
+	// though some look like integers.
+	const report = `OrderId;Code
+"A1";"0042"
+"A2";"abc"
+"A3";"7"
+`
+	st, err := stats(s1.NewReader(strings.NewReader(report)), profile(t, report))
+	if err != nil {
+		t.Fatalf("err=%v", err)
+	}
+	c := st.Columns[1]
+	if got, want := fmt.Sprintf("%s %s min=%s max=%s", c.Name, c.Type, orEmpty(c.Min), orEmpty(c.Max)), "Code String min=0042 max=abc"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"golang.org/x/text/encoding"
+
+	"github.com/atb-as/cleos/pkg/cleos/s1"
+)
+
+func validateCmd(args []string) error {
+	fs := newFlagSet("validate", "file.csv")
+	output := fs.String("o", "", "file to write the JSON report to instead of stdout")
+	strict := fs.Bool("strict", false, "fail on warnings, such as unknown columns")
+	minRows := fs.Int("min-rows", 1, "minimum number of rows")
+	maxRows := fs.Int("max-rows", 0, "maximum number of rows, 0 for no maximum")
+	maxProblems := fs.Int("max-problems", 100, "maximum number of errors and of warnings listed in the report, 0 for all")
+	encodingName := encodingFlag(fs)
+	args = parseArgs(fs, args, 1)
+
+	enc, err := reportEncoding(*encodingName)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	v, err := validate(file, validateOptions{
+		strict:      *strict,
+		minRows:     *minRows,
+		maxRows:     *maxRows,
+		maxProblems: *maxProblems,
+		encoding:    enc,
+	})
+	if err != nil {
+		return err
+	}
+	v.File = filepath.Base(args[0])
+
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if *output != "" {
+		err = ioutil.WriteFile(*output, b, 0644)
+	} else {
+		_, err = os.Stdout.Write(b)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !v.Valid {
+		return fmt.Errorf("%s is not valid: %d errors, %d warnings", args[0], v.ErrorCount, v.WarningCount)
+	}
+	return nil
+}
+
+// validateOptions control how reports are validated.
+type validateOptions struct {
+	// strict makes warnings fail the validation.
+	strict bool
+	// minRows and maxRows bound the number of rows. A maxRows of zero means
+	// no maximum.
+	minRows, maxRows int
+	// maxProblems limits the number of errors and of warnings listed, zero
+	// means no limit.
+	maxProblems int
+	// encoding is the character encoding of the report, or nil to detect it.
+	encoding encoding.Encoding
+}
+
+// validation is the result of validating an S-1 report.
+type validation struct {
+	File     string `json:"file,omitempty"`
+	Checksum string `json:"checksum"`
+	Valid    bool   `json:"valid"`
+	Rows     int    `json:"rows"`
+	// Columns are the columns of the header.
+	Columns      []string  `json:"columns"`
+	ErrorCount   int       `json:"errorCount"`
+	WarningCount int       `json:"warningCount"`
+	Errors       []problem `json:"errors"`
+	Warnings     []problem `json:"warnings"`
+
+	opts validateOptions
+}
+
+// problem is a finding of a check, found at Line, if any.
+type problem struct {
+	// Check is the check finding the problem: header, parse, duplicate or
+	// rows.
+	Check   string `json:"check"`
+	Line    int    `json:"line,omitempty"`
+	Column  string `json:"column,omitempty"`
+	Value   string `json:"value,omitempty"`
+	Message string `json:"message"`
+}
+
+func (v *validation) error(p problem) {
+	v.Errors = append(v.Errors, p)
+}
+
+func (v *validation) warning(p problem) {
+	v.Warnings = append(v.Warnings, p)
+}
+
+// validate checks the S-1 report read from r. It checks that the header has
+// the columns of s1.Columns and no others, that every row can be read and
+// every value decoded, that no row is repeated, and that the number of rows
+// is within bounds. Malformed reports, such as empty files or rows with
+// unbalanced quotes, are problems of the report. validate only fails if r
+// can not be read.
+func validate(r io.Reader, opts validateOptions) (*validation, error) {
+	h := sha256.New()
+	v := &validation{Columns: []string{}, opts: opts}
+	if err := v.check(io.TeeReader(r, h)); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case v.Rows < opts.minRows:
+		v.error(problem{Check: "rows", Message: fmt.Sprintf("%d rows, want at least %d", v.Rows, opts.minRows)})
+	case opts.maxRows > 0 && v.Rows > opts.maxRows:
+		v.error(problem{Check: "rows", Message: fmt.Sprintf("%d rows, want at most %d", v.Rows, opts.maxRows)})
+	}
+
+	// Drain r, in case the reader stopped short of the end, so the checksum
+	// covers the whole file.
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, err
+	}
+	v.Checksum = hex.EncodeToString(h.Sum(nil))
+
+	v.ErrorCount, v.WarningCount = len(v.Errors), len(v.Warnings)
+	v.Errors = firstProblems(v.Errors, opts.maxProblems)
+	v.Warnings = firstProblems(v.Warnings, opts.maxProblems)
+	v.Valid = v.ErrorCount == 0 && (!opts.strict || v.WarningCount == 0)
+	return v, nil
+}
+
+// check reads the report from r and records the problems found. It only
+// returns the errors of r.
+func (v *validation) check(r io.Reader) error {
+	reader := s1.NewReader(r)
+	reader.Lenient = true
+	reader.Encoding = v.opts.encoding
+
+	hdr, err := reader.Header()
+	if err == io.EOF {
+		v.error(problem{Check: "header", Line: 1, Message: "empty report, no header"})
+		return nil
+	}
+	if err != nil {
+		// The header can not be told from the rows after a malformed header.
+		return v.readError("header", err)
+	}
+	v.Columns = hdr
+	v.checkHeader(hdr)
+
+	// seen holds the line of the first occurrence of each row, by a hash of
+	// its values.
+	seen := make(map[[16]byte]int)
+	for {
+		row, err := reader.Row()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return v.readError("parse", err)
+		}
+		v.Rows++
+
+		key := rowKey(row)
+		if first, ok := seen[key]; ok {
+			v.error(problem{
+				Check:   "duplicate",
+				Line:    reader.Line(),
+				Message: fmt.Sprintf("row repeats the row on line %d", first),
+			})
+		} else {
+			seen[key] = reader.Line()
+		}
+	}
+
+	// The lenient reader skips rows that can not be split into values, or
+	// with values that can not be decoded.
+	for _, perr := range reader.Errors() {
+		v.Rows++
+		v.error(problem{
+			Check:   "parse",
+			Line:    perr.Line,
+			Column:  perr.Column,
+			Value:   perr.Value,
+			Message: perr.Err.Error(),
+		})
+	}
+	return nil
+}
+
+// readError records err, which stopped reading the report, as a problem of
+// the check if it is caused by the contents of the report, and otherwise
+// returns it.
+func (v *validation) readError(check string, err error) error {
+	var cerr *csv.ParseError
+	switch {
+	case errors.As(err, &cerr):
+		v.error(problem{Check: check, Line: cerr.Line, Message: cerr.Err.Error()})
+	case err == s1.ErrInvalidUTF8:
+		v.error(problem{Check: check, Message: err.Error()})
+	default:
+		return err
+	}
+	return nil
+}
+
+// firstProblems returns the first max of problems by line, or all of them if
+// max is zero. Problems without a line, such as those of the rows check, come
+// last.
+func firstProblems(problems []problem, max int) []problem {
+	sort.SliceStable(problems, func(i, j int) bool {
+		a, b := problems[i].Line, problems[j].Line
+		return a != 0 && (b == 0 || a < b)
+	})
+	if max > 0 && len(problems) > max {
+		problems = problems[:max]
+	}
+	if problems == nil {
+		problems = []problem{}
+	}
+	return problems
+}
+
+// checkHeader checks hdr against the columns of s1.Columns. Repeated and
+// missing columns are errors, while unknown columns are warnings, since
+// reports may be generated from templates with extra columns.
+func (v *validation) checkHeader(hdr []string) {
+	present := make(map[string]bool, len(hdr))
+	for _, name := range hdr {
+		switch {
+		case present[name]:
+			v.error(problem{Check: "header", Line: 1, Column: name, Message: "repeated column"})
+		case s1.Columns.Type(name) == s1.TypeUnknown:
+			v.warning(problem{Check: "header", Line: 1, Column: name, Message: "unknown column"})
+		}
+		present[name] = true
+	}
+
+	var missing []string
+	for name := range s1.Columns {
+		if !present[name] {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	for _, name := range missing {
+		v.error(problem{Check: "header", Line: 1, Column: name, Message: "missing column"})
+	}
+}
+
+// rowKey returns a hash of the values of row.
+func rowKey(row []interface{}) [16]byte {
+	h := fnv.New128a()
+	for _, v := range row {
+		fmt.Fprintf(h, "%T\x00%v\x00", v, v)
+	}
+	var key [16]byte
+	copy(key[:], h.Sum(nil))
+	return key
+}
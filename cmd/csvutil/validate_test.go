@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/atb-as/cleos/pkg/cleos/s1"
+)
+
+func TestValidate(t *testing.T) {
+	const report = `OrderId;Quantity;AmountInclVat;Quantity;Comment
+"A1";1;149.00;1;"x"
+"A2";x;149.00;1;"x"
+"A1";1;149.00;1;"x"
+"A3";1;149,00;1;"x"
+"A4";1
+`
+	v, err := validate(strings.NewReader(withStandardColumns(report)), validateOptions{minRows: 1})
+	if err != nil {
+		t.Fatalf("err=%v", err)
+	}
+
+	if v.Valid {
+		t.Errorf("got valid report")
+	}
+	if v.Rows != 5 {
+		t.Errorf("got %d rows, want 5", v.Rows)
+	}
+	if len(v.Checksum) != 64 {
+		t.Errorf("got checksum %q", v.Checksum)
+	}
+
+	var errs []string
+	for _, p := range v.Errors {
+		errs = append(errs, fmt.Sprintf("%s:%d:%s:%s", p.Check, p.Line, p.Column, p.Value))
+	}
+	want := []string{
+		"header:1:Quantity:",
+		"parse:3:Quantity:x",
+		"duplicate:4::",
+		"parse:5:AmountInclVat:149,00",
+		"parse:6::",
+	}
+	if !reflect.DeepEqual(errs, want) {
+		t.Errorf("got errors\n%q\nwant\n%q", errs, want)
+	}
+	if v.ErrorCount != len(want) {
+		t.Errorf("got error count %d, want %d", v.ErrorCount, len(want))
+	}
+
+	if len(v.Warnings) != 1 || v.Warnings[0].Column != "Comment" || v.Warnings[0].Message != "unknown column" {
+		t.Errorf("got warnings %+v, want unknown column Comment", v.Warnings)
+	}
+}
+
+func TestValidateHeader(t *testing.T) {
+	const report = "OrderId;Comment\n\"A1\";\"x\"\n"
+	v, err := validate(strings.NewReader(report), validateOptions{})
+	if err != nil {
+		t.Fatalf("err=%v", err)
+	}
+
+	if v.Valid {
+		t.Errorf("got valid report")
+	}
+	if v.ErrorCount != len(s1.Columns)-1 {
+		t.Errorf("got error count %d, want %d", v.ErrorCount, len(s1.Columns)-1)
+	}
+	if v.Errors[0].Column != "AccountingDate" || v.Errors[0].Message != "missing column" {
+		t.Errorf("got first error %+v, want missing column AccountingDate", v.Errors[0])
+	}
+	if len(v.Warnings) != 1 || v.Warnings[0].Column != "Comment" || v.Warnings[0].Message != "unknown column" {
+		t.Errorf("got warnings %+v, want unknown column Comment", v.Warnings)
+	}
+}
+
+// withStandardColumns returns report with the columns of s1.Columns it lacks
+// added to the end of each line, with empty values, so that no columns are
+// missing.
+func withStandardColumns(report string) string {
+	lines := strings.SplitAfter(report, "\n")
+	present := make(map[string]bool)
+	for _, name := range strings.Split(strings.TrimSuffix(lines[0], "\n"), ";") {
+		present[name] = true
+	}
+	var missing []string
+	for name := range s1.Columns {
+		if !present[name] {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+
+	var b strings.Builder
+	for i, line := range lines {
+		if line == "" {
+			continue
+		}
+		b.WriteString(strings.TrimSuffix(line, "\n"))
+		if i == 0 {
+			b.WriteString(";" + strings.Join(missing, ";"))
+		} else {
+			b.WriteString(strings.Repeat(";", len(missing)))
+		}
+		if strings.HasSuffix(line, "\n") {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+func TestValidateRows(t *testing.T) {
+	report := withStandardColumns("OrderId;Comment\n\"A1\";\"x\"\n\"A2\";\"y\"\n")
+	tests := []struct {
+		opts  validateOptions
+		valid bool
+	}{
+		{validateOptions{minRows: 1, maxRows: 2}, true},
+		{validateOptions{minRows: 3}, false},
+		{validateOptions{maxRows: 1}, false},
+		{validateOptions{strict: true}, false},
+	}
+
+	for _, tt := range tests {
+		v, err := validate(strings.NewReader(report), tt.opts)
+		if err != nil {
+			t.Fatalf("%+v: err=%v", tt.opts, err)
+		}
+		if v.Valid != tt.valid {
+			t.Errorf("%+v: got valid %v, want %v (errors %+v)", tt.opts, v.Valid, tt.valid, v.Errors)
+		}
+	}
+}
+
+func TestValidateMalformed(t *testing.T) {
+	tests := []struct {
+		name   string
+		report string
+		rows   int
+		want   []string
+	}{
+		{"empty", "", 0, []string{"header:1:empty report, no header", "rows:0:0 rows, want at least 1"}},
+		{"bare quote", "OrderId;Quantity\n\"A1\";1\n\"A\"2\";2\n\"A3\";3\n", 3, []string{`parse:3:extraneous or missing " in quoted-field`}},
+		{"unterminated quote", "OrderId;Quantity\n\"A1\";1\n\"A2;2\n", 2, []string{`parse:3:extraneous or missing " in quoted-field`}},
+	}
+
+	for _, tt := range tests {
+		v, err := validate(strings.NewReader(withStandardColumns(tt.report)), validateOptions{minRows: 1})
+		if err != nil {
+			t.Fatalf("%s: err=%v", tt.name, err)
+		}
+		if v.Valid || v.Rows != tt.rows || len(v.Checksum) != 64 {
+			t.Errorf("%s: got valid %v, %d rows and checksum %q, want invalid, %d rows and a checksum", tt.name, v.Valid, v.Rows, v.Checksum, tt.rows)
+		}
+		var errs []string
+		for _, p := range v.Errors {
+			errs = append(errs, fmt.Sprintf("%s:%d:%s", p.Check, p.Line, p.Message))
+		}
+		if !reflect.DeepEqual(errs, tt.want) {
+			t.Errorf("%s: got errors\n%q\nwant\n%q", tt.name, errs, tt.want)
+		}
+	}
+}
+
+func TestValidateMaxProblems(t *testing.T) {
+	// The duplicates are found before the parse error on an earlier line.
+	const report = "OrderId;Quantity\n\"A1\";1\n\"A2\";x\n\"A1\";1\n\"A1\";1\n"
+	v, err := validate(strings.NewReader(withStandardColumns(report)), validateOptions{maxProblems: 2})
+	if err != nil {
+		t.Fatalf("err=%v", err)
+	}
+
+	var errs []string
+	for _, p := range v.Errors {
+		errs = append(errs, fmt.Sprintf("%s:%d", p.Check, p.Line))
+	}
+	if want := []string{"parse:3", "duplicate:4"}; !reflect.DeepEqual(errs, want) {
+		t.Errorf("got errors %q, want %q", errs, want)
+	}
+	if v.ErrorCount != 3 {
+		t.Errorf("got error count %d, want 3", v.ErrorCount)
+	}
+}
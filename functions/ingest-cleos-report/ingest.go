@@ -0,0 +1,225 @@
+package ingest_report
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	_ "github.com/lib/pq"
+
+	"github.com/atb-as/cleos/pkg/cleos/postgres"
+	"github.com/atb-as/cleos/pkg/cleos/s1"
+)
+
+var (
+	tableName = os.Getenv("TABLE_NAME")
+
+	db            *sql.DB
+	storageClient *storage.Client
+)
+
+// Initialize global variables that may survive function invocations.
+func init() {
+	var err error
+	ctx := context.Background()
+
+	db, err = sql.Open("postgres", connString())
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Cloud Functions handle one request at a time per instance, so there is
+	// no point in keeping more than a single connection around.
+	db.SetMaxOpenConns(1)
+
+	storageClient, err = storage.NewClient(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// connString returns the connection string configured by the DB_*
+// environment variables. Connections through the Cloud SQL Unix socket never
+// leave the machine and default to sslmode=disable, while connections over
+// the network default to lib/pq's sslmode=require.
+func connString() string {
+	params := make(map[string]string)
+	for key, name := range map[string]string{
+		"host":        "DB_HOST",
+		"port":        "DB_PORT",
+		"user":        "DB_USER",
+		"password":    "DB_PASSWORD",
+		"dbname":      "DB_NAME",
+		"sslmode":     "DB_SSLMODE",
+		"sslrootcert": "DB_SSLROOTCERT",
+		"sslcert":     "DB_SSLCERT",
+		"sslkey":      "DB_SSLKEY",
+	} {
+		if v := os.Getenv(name); v != "" {
+			params[key] = v
+		}
+	}
+	if _, ok := params["sslmode"]; !ok && strings.HasPrefix(params["host"], "/") {
+		params["sslmode"] = "disable"
+	}
+	return postgres.ConnString(params)
+}
+
+// IngestCLEOSReport is triggered when FetchCLEOSReport has stored a report in
+// a cloud storage bucket. It parses the S-1 report contained in the object
+// identified by e, creates or migrates the destination table if needed and
+// loads every row in a single transaction, along with its provenance. The
+// outcome is recorded in the ledger of ingestions.
+//
+// Rows previously loaded from the same report are replaced, so retried
+// invocations do not produce duplicates. Failures that a retry would not fix,
+// such as objects not named after a report or reports that can not be
+// parsed, are logged rather than returned, so they are not retried.
+func IngestCLEOSReport(ctx context.Context, e GCSEvent) error {
+	ing := &postgres.Ingestion{
+		Filename:  e.Name,
+		TableName: tableName,
+		StartedAt: time.Now(),
+	}
+	var err error
+	if ing.ReportID, err = reportIDFromObjectName(e.Name); err != nil {
+		log.Printf("skipping %s: %v", e.Name, err)
+		return nil
+	}
+	if err := postgres.CreateLedger(ctx, db); err != nil {
+		return err
+	}
+
+	if err := ingestObject(ctx, e, ing); err != nil {
+		err = fmt.Errorf("ingest %s: %w", e.Name, err)
+		ing.Rows = 0
+		ing.Status = postgres.IngestionFailed
+		ing.Error = err.Error()
+		ing.FinishedAt = time.Now()
+		if rerr := postgres.RecordIngestion(ctx, db, ing); rerr != nil {
+			return fmt.Errorf("%v (recording the failed ingestion: %v)", err, rerr)
+		}
+		if permanent(err) {
+			log.Print(err)
+			return nil
+		}
+		return err
+	}
+
+	log.Printf("successfully ingested %d rows from report %s (%s) in %s (ingestion %d)",
+		ing.Rows, ing.ReportID, e.Name, ing.FinishedAt.Sub(ing.StartedAt), ing.ID)
+	return nil
+}
+
+// permanent reports whether err is bound to happen again if the ingestion is
+// retried, because the object is gone or the report in it is empty or can not
+// be parsed.
+func permanent(err error) bool {
+	var (
+		perr *s1.ParseError
+		cerr *csv.ParseError
+	)
+	return errors.As(err, &perr) || errors.As(err, &cerr) ||
+		errors.Is(err, s1.ErrInvalidUTF8) || errors.Is(err, io.EOF) ||
+		errors.Is(err, storage.ErrObjectNotExist)
+}
+
+// ingestObject loads the report in the object identified by e as recorded by
+// ing, setting the checksum of the report and the outcome.
+func ingestObject(ctx context.Context, e GCSEvent, ing *postgres.Ingestion) error {
+	obj := storageClient.Bucket(e.Bucket).Object(e.Name)
+
+	// The schema is inferred from the whole report, so it is read twice. The
+	// checksum is computed the same way as by csvutil, so that the ledger
+	// identifies a report regardless of how it was loaded.
+	r, err := obj.NewReader(ctx)
+	if err != nil {
+		return err
+	}
+	h := sha256.New()
+	columns, err := s1.Profile(s1.NewReader(io.TeeReader(r, h)), 0)
+	if err == nil {
+		// Profile stops at the last row, so drain anything after it.
+		_, err = io.Copy(h, r)
+	}
+	r.Close()
+	if err != nil {
+		return fmt.Errorf("profile: %w", err)
+	}
+	ing.Checksum = hex.EncodeToString(h.Sum(nil))
+
+	r, err = obj.NewReader(ctx)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	reader := s1.NewReader(r)
+	reader.Spec = s1.ProfileSpec(columns)
+	return ingest(ctx, columns, reader, ing)
+}
+
+// ingest loads all rows read from r into ing.TableName, creating it from
+// columns if it does not exist and migrating it if it lacks any of them, and
+// records the successful ingestion in the same transaction.
+func ingest(ctx context.Context, columns []s1.Column, r *s1.Reader, ing *postgres.Ingestion) error {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmts, err := postgres.Migrate(ctx, tx, ing.TableName, columns)
+	if err != nil {
+		return err
+	}
+	for _, stmt := range stmts {
+		log.Print(stmt)
+	}
+
+	deleteStmt := fmt.Sprintf("DELETE FROM %s WHERE %s = $1",
+		postgres.TableName(ing.TableName), postgres.ColumnName(postgres.ReportIDColumn))
+	if _, err := tx.ExecContext(ctx, deleteStmt, ing.ReportID); err != nil {
+		return err
+	}
+
+	prov := postgres.Provenance{SourceFile: ing.Filename, ReportID: ing.ReportID, LoadedAt: ing.StartedAt}
+	ing.Rows, err = postgres.Copy(ctx, tx, r, ing.TableName, prov, postgres.ConflictSkip)
+	if errors.Is(err, postgres.ErrCopyUnavailable) {
+		log.Printf("%v, inserting in batches", err)
+		ing.Rows, err = postgres.InsertBatches(ctx, tx, r, ing.TableName, prov, postgres.ConflictSkip)
+	}
+	if err != nil {
+		return err
+	}
+
+	ing.Status = postgres.IngestionSucceeded
+	ing.FinishedAt = time.Now()
+	if err := postgres.RecordIngestion(ctx, tx, ing); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// reportIDFromObjectName extracts the CLEOS report ID from the name of an
+// object stored by FetchCLEOSReport, which is on the form <id>_<filename>.
+func reportIDFromObjectName(name string) (string, error) {
+	i := strings.Index(name, "_")
+	if i <= 0 {
+		return "", fmt.Errorf("object name %q is not on the form <id>_<filename>", name)
+	}
+	if _, err := strconv.ParseUint(name[:i], 10, 64); err != nil {
+		return "", fmt.Errorf("object name %q does not start with a numeric report ID", name)
+	}
+	return name[:i], nil
+}
@@ -0,0 +1,19 @@
+package bigquery
+
+import "strings"
+
+var identifierEscaper = strings.NewReplacer("\\", "\\\\", "`", "\\`")
+
+// ColumnName returns name as a quoted identifier, safe for use in SQL
+// statements regardless of spaces or reserved words. Unlike in Postgres,
+// names are case insensitive in BigQuery, so they keep the case used in the
+// report.
+func ColumnName(name string) string {
+	return "`" + identifierEscaper.Replace(name) + "`"
+}
+
+// TableName returns name, which may be qualified with a dataset and project
+// name, as a quoted identifier.
+func TableName(name string) string {
+	return ColumnName(name)
+}
@@ -0,0 +1,126 @@
+// Package bigquery generates BigQuery schemas for S-1 reports.
+package bigquery
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/atb-as/cleos/pkg/cleos/s1"
+)
+
+// PartitionColumn is the column tables are partitioned on, if present.
+const PartitionColumn = "SalesDate"
+
+// Field is a column in a BigQuery JSON schema, as used by the bq command line
+// tool and the load job API.
+type Field struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// Mode is REQUIRED or NULLABLE.
+	Mode string `json:"mode"`
+}
+
+// Fields returns the BigQuery fields for the columns of an S-1 report, as
+// profiled by s1.Profile. Columns are REQUIRED unless the profile found empty
+// values in them.
+func Fields(columns []s1.Column) ([]Field, error) {
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no columns")
+	}
+	fields := make([]Field, len(columns))
+	for i, col := range columns {
+		typ, err := columnType(col)
+		if err != nil {
+			return nil, fmt.Errorf("column %d: %v", i, err)
+		}
+		mode := "REQUIRED"
+		if col.Nullable {
+			mode = "NULLABLE"
+		}
+		fields[i] = Field{Name: col.Name, Type: typ, Mode: mode}
+	}
+	return fields, nil
+}
+
+// JSONSchema returns the BigQuery JSON schema for the columns of an S-1
+// report, as profiled by s1.Profile.
+func JSONSchema(columns []s1.Column) ([]byte, error) {
+	fields, err := Fields(columns)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(fields, "", "  ")
+}
+
+// Schema returns a DDL statement for creating a table for the columns of an
+// S-1 report, as profiled by s1.Profile. The table is partitioned by day on
+// PartitionColumn if the report has it.
+func Schema(columns []s1.Column, tableName string) (string, error) {
+	fields, err := Fields(columns)
+	if err != nil {
+		return "", err
+	}
+	s := &strings.Builder{}
+
+	fmt.Fprintf(s, "CREATE TABLE IF NOT EXISTS %s (\n", TableName(tableName))
+	var partition string
+	for i, f := range fields {
+		fmt.Fprintf(s, "%s %s", ColumnName(f.Name), f.Type)
+		if f.Mode == "REQUIRED" {
+			s.WriteString(" NOT NULL")
+		}
+		if i != len(fields)-1 {
+			s.WriteString(",\n")
+		}
+		if strings.EqualFold(f.Name, PartitionColumn) {
+			partition = partitionExpr(f)
+		}
+	}
+	s.WriteString("\n)")
+	if partition != "" {
+		fmt.Fprintf(s, "\nPARTITION BY %s", partition)
+	}
+	s.WriteString(";")
+
+	return s.String(), nil
+}
+
+// columnType returns the BigQuery type of columns holding values of col.
+//
+// DateTime values are wall clock times without a time zone, so they are
+// stored as DATETIME, while Timestamp values are points in time. Amounts and
+// decimals fit in NUMERIC, which has 38 digits of precision.
+func columnType(col s1.Column) (string, error) {
+	switch col.Type {
+	case s1.TypeAmount, s1.TypeDecimal:
+		return "NUMERIC", nil
+	case s1.TypeDate:
+		return "DATE", nil
+	case s1.TypeDateTime:
+		return "DATETIME", nil
+	case s1.TypeTimestamp:
+		return "TIMESTAMP", nil
+	case s1.TypeInteger:
+		return "INT64", nil
+	case s1.TypeString, s1.TypeUnknown:
+		return "STRING", nil
+	default:
+		return "", fmt.Errorf("unrecognized type %s", col.Type)
+	}
+}
+
+// partitionExpr returns the expression partitioning a table by day on f, or
+// "" if f can not be partitioned on.
+func partitionExpr(f Field) string {
+	switch f.Type {
+	case "DATE":
+		return ColumnName(f.Name)
+	case "DATETIME":
+		return fmt.Sprintf("DATETIME_TRUNC(%s, DAY)", ColumnName(f.Name))
+	case "TIMESTAMP":
+		return fmt.Sprintf("TIMESTAMP_TRUNC(%s, DAY)", ColumnName(f.Name))
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,104 @@
+package bigquery
+
+import (
+	"testing"
+
+	"github.com/atb-as/cleos/pkg/cleos/s1"
+)
+
+var columns = []s1.Column{
+	{Name: "OrderId", Type: s1.TypeString},
+	{Name: "Quantity", Type: s1.TypeInteger, Min: -1, Max: 2},
+	{Name: "AmountInclVat", Type: s1.TypeAmount, Nullable: true},
+	{Name: "VatRate", Type: s1.TypeDecimal},
+	{Name: "SalesDate", Type: s1.TypeDate},
+	{Name: "SalesDateTime", Type: s1.TypeDateTime},
+	{Name: "Created", Type: s1.TypeTimestamp, Nullable: true},
+	{Name: "Empty", Type: s1.TypeUnknown, Nullable: true},
+}
+
+func TestSchema(t *testing.T) {
+	got, err := Schema(columns, "project.reports.sales")
+	if err != nil {
+		t.Fatalf("err=%v", err)
+	}
+	want := "CREATE TABLE IF NOT EXISTS `project.reports.sales` (\n" +
+		"`OrderId` STRING NOT NULL,\n" +
+		"`Quantity` INT64 NOT NULL,\n" +
+		"`AmountInclVat` NUMERIC,\n" +
+		"`VatRate` NUMERIC NOT NULL,\n" +
+		"`SalesDate` DATE NOT NULL,\n" +
+		"`SalesDateTime` DATETIME NOT NULL,\n" +
+		"`Created` TIMESTAMP,\n" +
+		"`Empty` STRING\n" +
+		")\n" +
+		"PARTITION BY `SalesDate`;"
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSchemaPartition(t *testing.T) {
+	tests := []struct {
+		typ  s1.Type
+		want string
+	}{
+		{s1.TypeDateTime, "\nPARTITION BY DATETIME_TRUNC(`salesdate`, DAY);"},
+		{s1.TypeTimestamp, "\nPARTITION BY TIMESTAMP_TRUNC(`salesdate`, DAY);"},
+		{s1.TypeString, "NOT NULL\n);"},
+	}
+
+	for _, tt := range tests {
+		got, err := Schema([]s1.Column{{Name: "salesdate", Type: tt.typ}}, "sales")
+		if err != nil {
+			t.Fatalf("%s: err=%v", tt.typ, err)
+		}
+		if want := tt.want; got[len(got)-len(want):] != want {
+			t.Errorf("%s: got\n%s\nwant suffix\n%s", tt.typ, got, want)
+		}
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	got, err := JSONSchema(columns[:3])
+	if err != nil {
+		t.Fatalf("err=%v", err)
+	}
+	want := `[
+  {
+    "name": "OrderId",
+    "type": "STRING",
+    "mode": "REQUIRED"
+  },
+  {
+    "name": "Quantity",
+    "type": "INT64",
+    "mode": "REQUIRED"
+  },
+  {
+    "name": "AmountInclVat",
+    "type": "NUMERIC",
+    "mode": "NULLABLE"
+  }
+]`
+	if string(got) != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	names := []struct {
+		arg  string
+		want string
+	}{
+		{"OrderId", "`OrderId`"},
+		{"Order", "`Order`"},
+		{"a`b", "`a\\`b`"},
+	}
+
+	for _, tt := range names {
+		if got := ColumnName(tt.arg); got != tt.want {
+			t.Errorf("ColumnName(%q): got %s, want %s", tt.arg, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+// Package parquet writes S-1 reports as Parquet files.
+package parquet
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/xitongsys/parquet-go/writer"
+
+	"github.com/atb-as/cleos/pkg/cleos/s1"
+)
+
+// DefaultRowGroupSize is the default size of row groups. Large row groups
+// make for efficient scans of large files, at the cost of memory while
+// writing.
+const DefaultRowGroupSize = 128 << 20
+
+// A Writer writes the rows of an S-1 report as a Parquet file, with a column
+// for each column of the report.
+//
+// Dates are DATE, date times and timestamps TIMESTAMP_MICROS, integers INT64,
+// amounts DECIMAL(18,2), decimals DOUBLE and strings UTF8. Columns are
+// required unless the profile of the report found empty values in them.
+type Writer struct {
+	// RowGroupSize is the approximate size in bytes of the row groups
+	// written, DefaultRowGroupSize by default. It must be set before the
+	// first call to Write.
+	RowGroupSize int64
+
+	columns []s1.Column
+	pw      *writer.CSVWriter
+	started bool
+}
+
+// NewWriter returns a Writer writing to w the rows of an S-1 report with
+// columns, as profiled by s1.Profile. The profile should cover the whole
+// report, since empty values are only allowed in columns found nullable.
+func NewWriter(w io.Writer, columns []s1.Column) (*Writer, error) {
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no columns")
+	}
+	md := make([]string, len(columns))
+	for i, col := range columns {
+		field, err := schemaField(col)
+		if err != nil {
+			return nil, fmt.Errorf("column %d: %v", i, err)
+		}
+		md[i] = field
+	}
+
+	pw, err := writer.NewCSVWriterFromWriter(md, w, 4)
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{
+		RowGroupSize: DefaultRowGroupSize,
+		columns:      columns,
+		pw:           pw,
+	}, nil
+}
+
+// schemaField returns the schema of the Parquet column for col, in the
+// metadata format of parquet-go.
+func schemaField(col s1.Column) (string, error) {
+	if strings.ContainsAny(col.Name, ",=") {
+		return "", fmt.Errorf("unsupported column name %q", col.Name)
+	}
+	var typ string
+	switch col.Type {
+	case s1.TypeDate:
+		typ = "type=INT32, convertedtype=DATE"
+	case s1.TypeDateTime, s1.TypeTimestamp:
+		typ = "type=INT64, convertedtype=TIMESTAMP_MICROS"
+	case s1.TypeInteger:
+		typ = "type=INT64"
+	case s1.TypeAmount:
+		// Amounts are stored in øre as an int64, which has at most 18 digits
+		// of full range.
+		typ = "type=INT64, convertedtype=DECIMAL, precision=18, scale=2"
+	case s1.TypeDecimal:
+		typ = "type=DOUBLE"
+	case s1.TypeString, s1.TypeUnknown:
+		typ = "type=BYTE_ARRAY, convertedtype=UTF8"
+	default:
+		return "", fmt.Errorf("unrecognized type %s", col.Type)
+	}
+	repetition := "REQUIRED"
+	if col.Nullable {
+		repetition = "OPTIONAL"
+	}
+	return fmt.Sprintf("name=%s, %s, repetitiontype=%s", col.Name, typ, repetition), nil
+}
+
+// Write writes a row as returned by s1.Reader.Row, with a Spec made from the
+// profile of the report by s1.ProfileSpec so that values are decoded by the
+// type of their column.
+func (w *Writer) Write(row []interface{}) error {
+	if len(row) != len(w.columns) {
+		return fmt.Errorf("got %d values, want %d", len(row), len(w.columns))
+	}
+	if !w.started {
+		w.pw.RowGroupSize = w.RowGroupSize
+		w.started = true
+	}
+
+	// Rows are buffered until a row group is complete, so each row needs a
+	// record of its own.
+	rec := make([]interface{}, len(row))
+	for i, v := range row {
+		col := w.columns[i]
+		pv, err := value(col.Type, v)
+		if err != nil {
+			return fmt.Errorf("column %s: %v", col.Name, err)
+		}
+		if pv == nil && !col.Nullable {
+			return fmt.Errorf("column %s: empty value in column not found nullable", col.Name)
+		}
+		rec[i] = pv
+	}
+	return w.pw.Write(rec)
+}
+
+// Close writes any buffered rows and the footer of the file. It does not
+// close the underlying writer.
+func (w *Writer) Close() error {
+	return w.pw.WriteStop()
+}
+
+// value returns v, a value in a column of type t, as the Go type parquet-go
+// expects for the column, or nil if v is empty.
+func value(t s1.Type, v interface{}) (interface{}, error) {
+	if _, ok := v.(s1.Null); ok || v == nil {
+		return nil, nil
+	}
+
+	switch t {
+	case s1.TypeDate:
+		if v, ok := v.(time.Time); ok {
+			// Dates are calendar dates, counted in days since the epoch
+			// regardless of their time zone.
+			d := time.Date(v.Year(), v.Month(), v.Day(), 0, 0, 0, 0, time.UTC)
+			return int32(d.Unix() / (24 * 60 * 60)), nil
+		}
+	case s1.TypeDateTime, s1.TypeTimestamp:
+		if v, ok := v.(time.Time); ok {
+			return v.UnixNano() / int64(time.Microsecond), nil
+		}
+	case s1.TypeInteger:
+		switch v := v.(type) {
+		case int:
+			return int64(v), nil
+		case int64:
+			return v, nil
+		}
+	case s1.TypeAmount:
+		if v, ok := v.(s1.Amount); ok {
+			return int64(v), nil
+		}
+	case s1.TypeDecimal:
+		switch v := v.(type) {
+		case float64:
+			return v, nil
+		case int:
+			return float64(v), nil
+		case int64:
+			return float64(v), nil
+		}
+	case s1.TypeString, s1.TypeUnknown:
+		if v, ok := v.(string); ok {
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported value %v of type %T", v, v)
+}
@@ -0,0 +1,128 @@
+package parquet
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/reader"
+
+	"github.com/atb-as/cleos/pkg/cleos/s1"
+)
+
+const report = `SalesDate;SalesDateTime;OrderId;Quantity;AmountInclVat;VatRate;Note
+01.03.2020;29.03.2020 12:30:00;"A1";2;-149.00;12.5;
+02.03.2020;01.03.2020 00:00:00;"A2";1;0.50;0.0;"ok"
+`
+
+type row struct {
+	SalesDate     int32   `parquet:"name=SalesDate, type=INT32, convertedtype=DATE"`
+	SalesDateTime int64   `parquet:"name=SalesDateTime, type=INT64, convertedtype=TIMESTAMP_MICROS"`
+	OrderId       string  `parquet:"name=OrderId, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Quantity      int64   `parquet:"name=Quantity, type=INT64"`
+	AmountInclVat int64   `parquet:"name=AmountInclVat, type=INT64, convertedtype=DECIMAL, precision=18, scale=2"`
+	VatRate       float64 `parquet:"name=VatRate, type=DOUBLE"`
+	Note          *string `parquet:"name=Note, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+}
+
+func TestWriter(t *testing.T) {
+	columns, err := s1.Profile(s1.NewReader(strings.NewReader(report)), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	w, err := NewWriter(&b, columns)
+	if err != nil {
+		t.Fatalf("err=%v", err)
+	}
+	r := s1.NewReader(strings.NewReader(report))
+	r.Spec = s1.ProfileSpec(columns)
+	for {
+		row, err := r.Row()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Write(row); err != nil {
+			t.Fatalf("err=%v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("err=%v", err)
+	}
+
+	f, err := buffer.NewBufferFile(b.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pr, err := reader.NewParquetReader(f, new(row), 1)
+	if err != nil {
+		t.Fatalf("err=%v", err)
+	}
+	defer pr.ReadStop()
+
+	var types []string
+	for _, el := range pr.Footer.Schema[1:] {
+		typ := el.GetType().String()
+		if el.IsSetConvertedType() {
+			typ += "/" + el.GetConvertedType().String()
+		}
+		if el.GetRepetitionType() == parquet.FieldRepetitionType_OPTIONAL {
+			typ += "?"
+		}
+		types = append(types, typ)
+	}
+	wantTypes := []string{
+		"INT32/DATE",
+		"INT64/TIMESTAMP_MICROS",
+		"BYTE_ARRAY/UTF8",
+		"INT64",
+		"INT64/DECIMAL",
+		"DOUBLE",
+		"BYTE_ARRAY/UTF8?",
+	}
+	if !reflect.DeepEqual(types, wantTypes) {
+		t.Errorf("got types %v, want %v", types, wantTypes)
+	}
+
+	rows := make([]row, pr.GetNumRows())
+	if err := pr.Read(&rows); err != nil {
+		t.Fatalf("err=%v", err)
+	}
+	empty, ok := "", "ok"
+	want := []row{
+		{18322, 1585477800000000, "A1", 2, -14900, 12.5, &empty},
+		{18323, 1583017200000000, "A2", 1, 50, 0, &ok},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("got\n%+v\nwant\n%+v", rows, want)
+	}
+}
+
+func TestWriterNotNullable(t *testing.T) {
+	w, err := NewWriter(ioutil.Discard, []s1.Column{{Name: "Quantity", Type: s1.TypeInteger}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write([]interface{}{s1.Null{Type: s1.TypeInteger}}); err == nil {
+		t.Errorf("got no error for empty value in column not found nullable")
+	}
+}
+
+func TestWriterString(t *testing.T) {
+	w, err := NewWriter(ioutil.Discard, []s1.Column{{Name: "Code", Type: s1.TypeString}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write([]interface{}{42}); err == nil {
+		t.Errorf("got no error for integer in column of strings")
+	}
+}
@@ -0,0 +1,25 @@
+package postgres
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var connValueEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// ConnString returns params as a connection string on the key=value form
+// understood by lib/pq, with keys in sorted order and values quoted.
+func ConnString(params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = fmt.Sprintf("%s='%s'", key, connValueEscaper.Replace(params[key]))
+	}
+	return strings.Join(parts, " ")
+}
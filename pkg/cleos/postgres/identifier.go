@@ -0,0 +1,25 @@
+package postgres
+
+import (
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// ColumnName returns name as a quoted identifier, safe for use in SQL
+// statements regardless of spaces, non-ASCII letters or reserved words.
+// Names are lower cased first, so they match the names of columns created
+// from unquoted identifiers.
+func ColumnName(name string) string {
+	return pq.QuoteIdentifier(strings.ToLower(name))
+}
+
+// TableName returns name, which may be qualified with a schema name, as
+// quoted identifiers. It is normalised the same way as ColumnName.
+func TableName(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = ColumnName(part)
+	}
+	return strings.Join(parts, ".")
+}
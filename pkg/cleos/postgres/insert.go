@@ -0,0 +1,239 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// RowReader reads the rows of an S-1 report, like *s1.Reader does.
+type RowReader interface {
+	Header() ([]string, error)
+	Row() ([]interface{}, error)
+	// Line returns the line of the row last read.
+	Line() int
+}
+
+// ErrCopyUnavailable is returned by Copy when the server does not support
+// COPY, in which case the rows can be inserted by InsertBatches instead.
+var ErrCopyUnavailable = errors.New("postgres: copy not available")
+
+// copyTable is the temporary table rows are copied into before being
+// inserted into the destination table, since COPY can not skip or update
+// rows already loaded.
+const copyTable = "cleos_copy"
+
+// Copy streams the rows read from r through COPY into tableName, a table
+// created by Schema, along with their provenance, and returns the number of
+// rows added. Rows already loaded from the same line of the same report file
+// are handled according to action, so loading a file twice is safe. Skipped
+// rows are not counted, while updated rows are.
+//
+// If COPY is not available, Copy returns an error wrapping
+// ErrCopyUnavailable before reading any rows, and leaves tx as it was.
+func Copy(ctx context.Context, tx *sql.Tx, r RowReader, tableName string, prov Provenance, action ConflictAction) (int64, error) {
+	header, err := provenanceHeader(r)
+	if err != nil {
+		return 0, err
+	}
+	stmt, err := prepareCopy(ctx, tx, tableName, header)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrCopyUnavailable, err)
+	}
+	defer stmt.Close()
+
+	for {
+		row, err := r.Row()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		row = append(row, prov.Values(r.Line())...)
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			return 0, err
+		}
+	}
+	// Flush the rows buffered by the driver.
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return 0, err
+	}
+
+	cols := columnList(header)
+	insert := fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s %s",
+		TableName(tableName), cols, cols, pq.QuoteIdentifier(copyTable), OnConflict(header, action))
+	res, err := tx.ExecContext(ctx, insert)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, "DROP TABLE "+pq.QuoteIdentifier(copyTable)); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// prepareCopy creates copyTable like tableName and prepares a COPY of header
+// into it. The transaction is left as it was if preparing fails.
+func prepareCopy(ctx context.Context, tx *sql.Tx, tableName string, header []string) (*sql.Stmt, error) {
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT "+copyTable); err != nil {
+		return nil, err
+	}
+	stmt, err := func() (*sql.Stmt, error) {
+		create := fmt.Sprintf("CREATE TEMPORARY TABLE %s (LIKE %s INCLUDING DEFAULTS) ON COMMIT DROP",
+			pq.QuoteIdentifier(copyTable), TableName(tableName))
+		if _, err := tx.ExecContext(ctx, create); err != nil {
+			return nil, err
+		}
+		names := make([]string, len(header))
+		for i, name := range header {
+			names[i] = strings.ToLower(name)
+		}
+		return tx.PrepareContext(ctx, pq.CopyIn(copyTable, names...))
+	}()
+	if err != nil {
+		if _, rerr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+copyTable); rerr != nil {
+			return nil, rerr
+		}
+		return nil, err
+	}
+	return stmt, nil
+}
+
+// maxBatchSize is the maximum number of rows inserted by a single statement.
+// Postgres also limits the number of parameters of a statement to 65535.
+const maxBatchSize = 500
+
+// InsertBatches inserts the rows read from r into tableName like Copy does,
+// with INSERT statements of several rows each.
+func InsertBatches(ctx context.Context, tx *sql.Tx, r RowReader, tableName string, prov Provenance, action ConflictAction) (int64, error) {
+	header, err := provenanceHeader(r)
+	if err != nil {
+		return 0, err
+	}
+	size := maxBatchSize
+	if max := 65535 / len(header); size > max {
+		size = max
+	}
+
+	var stmt *sql.Stmt
+	defer func() {
+		if stmt != nil {
+			stmt.Close()
+		}
+	}()
+
+	var (
+		n    int64
+		args = make([]interface{}, 0, size*len(header))
+	)
+	for {
+		row, err := r.Row()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		args = append(args, row...)
+		args = append(args, prov.Values(r.Line())...)
+		if len(args) < cap(args) {
+			continue
+		}
+
+		if stmt == nil {
+			stmt, err = tx.PrepareContext(ctx, insertStatement(tableName, header, size, action))
+			if err != nil {
+				return 0, err
+			}
+		}
+		res, err := stmt.ExecContext(ctx, args...)
+		if err := addRowsAffected(&n, res, err); err != nil {
+			return 0, err
+		}
+		args = args[:0]
+	}
+
+	if len(args) > 0 {
+		insert := insertStatement(tableName, header, len(args)/len(header), action)
+		res, err := tx.ExecContext(ctx, insert, args...)
+		if err := addRowsAffected(&n, res, err); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+// provenanceHeader returns the header of r followed by the provenance
+// columns.
+func provenanceHeader(r RowReader) ([]string, error) {
+	header, err := r.Header()
+	if err != nil {
+		return nil, err
+	}
+	return append(header[:len(header):len(header)], ProvenanceColumns()...), nil
+}
+
+// addRowsAffected adds the number of rows affected by a statement with the
+// result res and error err to n, and returns any error.
+func addRowsAffected(n *int64, res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	*n += affected
+	return nil
+}
+
+// insertStatement returns a statement inserting rows rows of header into
+// tableName.
+func insertStatement(tableName string, header []string, rows int, action ConflictAction) string {
+	var s strings.Builder
+	fmt.Fprintf(&s, "INSERT INTO %s (%s) VALUES ", TableName(tableName), columnList(header))
+	for i := 0; i < rows; i++ {
+		if i > 0 {
+			s.WriteString(",")
+		}
+		s.WriteString(placeholders(i*len(header), len(header)))
+	}
+	s.WriteString(" " + OnConflict(header, action))
+	return s.String()
+}
+
+// placeholders returns a parenthesized list of n placeholders, numbered from
+// offset+1.
+func placeholders(offset, n int) string {
+	var s strings.Builder
+	s.WriteString("(")
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			s.WriteString(",")
+		}
+		fmt.Fprintf(&s, "$%d", offset+i+1)
+	}
+	s.WriteString(")")
+	return s.String()
+}
+
+// columnList returns the quoted names of header, separated by commas.
+func columnList(header []string) string {
+	names := make([]string, len(header))
+	for i, name := range header {
+		names[i] = ColumnName(name)
+	}
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,14 @@
+package postgres
+
+import "testing"
+
+func TestInsertStatement(t *testing.T) {
+	header := append([]string{"OrderId", "Quantity"}, ProvenanceColumns()...)
+	got := insertStatement("sales", header, 2, ConflictSkip)
+	want := `INSERT INTO "sales" ("orderid","quantity","cleos_source_file","cleos_report_id","cleos_line","cleos_loaded_at") ` +
+		`VALUES ($1,$2,$3,$4,$5,$6),($7,$8,$9,$10,$11,$12) ` +
+		`ON CONFLICT ("cleos_report_id","cleos_source_file","cleos_line") DO NOTHING`
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,123 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// LedgerTable is the table recording the ingestions of S-1 reports.
+const LedgerTable = "cleos_ingestions"
+
+// IngestionStatus is the outcome of an ingestion.
+type IngestionStatus string
+
+const (
+	IngestionSucceeded IngestionStatus = "succeeded"
+	IngestionFailed    IngestionStatus = "failed"
+)
+
+// Ingestion is an entry in LedgerTable, recording the loading of an S-1
+// report file into a table.
+type Ingestion struct {
+	ID        int64
+	ReportID  string
+	Filename  string
+	Checksum  string
+	TableName string
+	// Rows is the number of rows added to the table, or updated when rows
+	// already loaded are updated rather than skipped.
+	Rows       int64
+	Status     IngestionStatus
+	Error      string
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// Execer is implemented by *sql.DB, *sql.Conn and *sql.Tx.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// CreateLedger creates LedgerTable if it does not exist.
+func CreateLedger(ctx context.Context, e Execer) error {
+	_, err := e.ExecContext(ctx, fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %[1]s (
+"id" bigserial PRIMARY KEY,
+"report_id" text NOT NULL,
+"filename" text NOT NULL,
+"checksum" text NOT NULL,
+"table_name" text NOT NULL,
+"row_count" bigint NOT NULL,
+"status" text NOT NULL,
+"error" text,
+"started_at" timestamptz NOT NULL,
+"finished_at" timestamptz NOT NULL
+);
+CREATE INDEX IF NOT EXISTS %[2]s ON %[1]s ("checksum");`,
+		TableName(LedgerTable), ColumnName(LedgerTable+"_checksum")))
+	return err
+}
+
+// LastIngestion returns the most recent successful ingestion of a report file
+// with the given checksum, or nil if there is none.
+//
+// When q is a transaction, the checksum stays locked until the transaction
+// ends, so that concurrent ingestions of the same file are serialized rather
+// than both finding no previous ingestion.
+func LastIngestion(ctx context.Context, q Queryer, checksum string) (*Ingestion, error) {
+	const lock = `SELECT pg_advisory_xact_lock(hashtext($1))`
+	rows, err := q.QueryContext(ctx, lock, LedgerTable+":"+checksum)
+	if err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	query := fmt.Sprintf(`
+SELECT "id", "report_id", "filename", "checksum", "table_name", "row_count", "status", coalesce("error", ''), "started_at", "finished_at"
+FROM %s
+WHERE "checksum" = $1 AND "status" = $2
+ORDER BY "finished_at" DESC
+LIMIT 1`, TableName(LedgerTable))
+	rows, err = q.QueryContext(ctx, query, checksum, IngestionSucceeded)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	var ing Ingestion
+	err = rows.Scan(&ing.ID, &ing.ReportID, &ing.Filename, &ing.Checksum, &ing.TableName,
+		&ing.Rows, &ing.Status, &ing.Error, &ing.StartedAt, &ing.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &ing, nil
+}
+
+// RecordIngestion adds ing to LedgerTable and sets its ID. A successful
+// ingestion should be recorded in the transaction loading the rows, so that
+// the ledger never disagrees with the table.
+func RecordIngestion(ctx context.Context, q Queryer, ing *Ingestion) error {
+	query := fmt.Sprintf(`
+INSERT INTO %s ("report_id", "filename", "checksum", "table_name", "row_count", "status", "error", "started_at", "finished_at")
+VALUES ($1, $2, $3, $4, $5, $6, nullif($7, ''), $8, $9)
+RETURNING "id"`, TableName(LedgerTable))
+	rows, err := q.QueryContext(ctx, query, ing.ReportID, ing.Filename, ing.Checksum, ing.TableName,
+		ing.Rows, ing.Status, ing.Error, ing.StartedAt, ing.FinishedAt)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return fmt.Errorf("no id returned for ingestion")
+	}
+	return rows.Scan(&ing.ID)
+}
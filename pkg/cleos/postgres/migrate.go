@@ -0,0 +1,245 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/atb-as/cleos/pkg/cleos/s1"
+)
+
+// TableColumn describes a column of an existing table.
+type TableColumn struct {
+	Name string
+	// Type is the type of the column, named the way Schema names it where
+	// possible, e.g. "int", "numeric(19,2)" or "timestamptz".
+	Type     string
+	Nullable bool
+}
+
+// Queryer is implemented by *sql.DB, *sql.Conn and *sql.Tx.
+type Queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// Columns returns the columns of the table tableName, as found in
+// information_schema. It returns no columns if the table does not exist.
+func Columns(ctx context.Context, q Queryer, tableName string) ([]TableColumn, error) {
+	const query = `
+SELECT column_name, data_type, is_nullable = 'YES', numeric_precision, numeric_scale
+FROM information_schema.columns
+WHERE table_schema = coalesce($1, current_schema()) AND table_name = $2
+ORDER BY ordinal_position`
+
+	var schema sql.NullString
+	name := strings.ToLower(tableName)
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		schema = sql.NullString{String: name[:i], Valid: true}
+		name = name[i+1:]
+	}
+
+	rows, err := q.QueryContext(ctx, query, schema, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []TableColumn
+	for rows.Next() {
+		var (
+			col              TableColumn
+			dataType         string
+			precision, scale sql.NullInt64
+		)
+		if err := rows.Scan(&col.Name, &dataType, &col.Nullable, &precision, &scale); err != nil {
+			return nil, err
+		}
+		col.Type = typeName(dataType, precision, scale)
+		columns = append(columns, col)
+	}
+	return columns, rows.Err()
+}
+
+// LockMigration serializes migrations of the table tableName by transactions
+// of concurrent loads, until the end of the transaction q. Columns must be
+// read again once the lock is held, since a concurrent transaction may have
+// migrated the table in the meantime.
+func LockMigration(ctx context.Context, q Queryer, tableName string) error {
+	const lock = `SELECT pg_advisory_xact_lock(hashtext($1))`
+	rows, err := q.QueryContext(ctx, lock, "migrate:"+strings.ToLower(tableName))
+	if err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
+// TableMigration returns the statements needed for tableName to hold
+// columns, as profiled by s1.Profile, creating the table if it does not
+// exist.
+func TableMigration(ctx context.Context, q Queryer, tableName string, columns []s1.Column) ([]string, error) {
+	table, err := Columns(ctx, q, tableName)
+	if err != nil {
+		return nil, err
+	}
+	if len(table) == 0 {
+		schema, err := Schema(columns, tableName)
+		if err != nil {
+			return nil, err
+		}
+		return []string{schema}, nil
+	}
+	return Migration(tableName, table, columns)
+}
+
+// Migrate creates tableName, or migrates it to hold columns, in the
+// transaction tx, and returns the statements executed. Concurrent loads only
+// wait for each other when the table must be changed, and the table is read
+// again once they do, since another load may have changed it in the meantime.
+func Migrate(ctx context.Context, tx *sql.Tx, tableName string, columns []s1.Column) ([]string, error) {
+	stmts, err := TableMigration(ctx, tx, tableName, columns)
+	if err != nil || len(stmts) == 0 {
+		return nil, err
+	}
+	if err := LockMigration(ctx, tx, tableName); err != nil {
+		return nil, err
+	}
+	if stmts, err = TableMigration(ctx, tx, tableName, columns); err != nil {
+		return nil, err
+	}
+
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return nil, fmt.Errorf("%s: %v", stmt, err)
+		}
+	}
+	return stmts, nil
+}
+
+// typeName returns the name Schema uses for the information_schema data type
+// dataType.
+func typeName(dataType string, precision, scale sql.NullInt64) string {
+	switch dataType {
+	case "integer":
+		return "int"
+	case "timestamp with time zone":
+		return "timestamptz"
+	case "numeric":
+		if precision.Valid && scale.Valid {
+			return fmt.Sprintf("numeric(%d,%d)", precision.Int64, scale.Int64)
+		}
+		return "numeric"
+	default:
+		return dataType
+	}
+}
+
+// Migration returns the SQL statements needed for the existing table
+// tableName, with the columns in table, to hold the columns of an S-1 report
+// as profiled by s1.Profile. It returns no statements if the table already
+// holds the columns.
+//
+// Missing columns are added as nullable columns, since existing rows have no
+// values for them. Columns are widened when needed, e.g. from int to bigint,
+// from bigint to numeric, from date to timestamptz and from anything to text.
+// Columns are never narrowed, and are left alone when the report has no
+// values for them, as their type is then unknown. NOT NULL constraints are
+// dropped from columns with empty values in the report, and from columns
+// missing in the report.
+// Provenance columns missing in tables created before they were introduced
+// are added along with their unique index.
+func Migration(tableName string, table []TableColumn, columns []s1.Column) ([]string, error) {
+	existing := make(map[string]TableColumn, len(table))
+	for _, col := range table {
+		existing[col.Name] = col
+	}
+
+	var stmts []string
+	alter := func(format string, args ...interface{}) {
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s %s;", TableName(tableName), fmt.Sprintf(format, args...)))
+	}
+
+	var indexed bool
+	for _, col := range provenanceColumns {
+		if _, ok := existing[col.Name]; !ok {
+			indexed = true
+		}
+	}
+	columns = append(columns[:len(columns):len(columns)], provenanceColumns...)
+
+	reported := make(map[string]bool, len(columns))
+	for i, col := range columns {
+		typ, err := columnType(col)
+		if err != nil {
+			return nil, fmt.Errorf("column %d: %v", i, err)
+		}
+
+		name := strings.ToLower(col.Name)
+		reported[name] = true
+		have, ok := existing[name]
+		if !ok {
+			alter("ADD COLUMN %s %s", ColumnName(name), typ)
+			continue
+		}
+		if wider, ok := widenType(have.Type, typ); ok && col.Type != s1.TypeUnknown {
+			alter("ALTER COLUMN %s TYPE %s", ColumnName(name), wider)
+		}
+		if col.Nullable && !have.Nullable {
+			alter("ALTER COLUMN %s DROP NOT NULL", ColumnName(name))
+		}
+	}
+
+	for _, have := range table {
+		if !reported[have.Name] && !have.Nullable {
+			alter("ALTER COLUMN %s DROP NOT NULL", ColumnName(have.Name))
+		}
+	}
+	if indexed {
+		stmts = append(stmts, provenanceIndex(tableName))
+	}
+
+	return stmts, nil
+}
+
+// Ranks of the types able to hold numbers and points in time, from the
+// narrowest to the widest.
+var (
+	numericRanks = map[string]int{"smallint": 1, "int": 2, "bigint": 3, "numeric": 5}
+	timeRanks    = map[string]int{"date": 1, "timestamptz": 2}
+)
+
+func numericRank(typ string) (int, bool) {
+	if strings.HasPrefix(typ, "numeric(") {
+		return 4, true
+	}
+	rank, ok := numericRanks[typ]
+	return rank, ok
+}
+
+// widenType returns the type a column of type have must be changed to in
+// order to hold values of type want, and false if have already holds them.
+// Columns of types Schema never creates are left alone.
+func widenType(have, want string) (string, bool) {
+	if have == want || have == "text" {
+		return "", false
+	}
+	if want == "text" {
+		return "text", true
+	}
+
+	haveNumeric, hn := numericRank(have)
+	wantNumeric, wn := numericRank(want)
+	haveTime, ht := timeRanks[have]
+	wantTime, wt := timeRanks[want]
+	switch {
+	case hn && wn:
+		return want, wantNumeric > haveNumeric
+	case ht && wt:
+		return want, wantTime > haveTime
+	case (hn || ht) && (wn || wt):
+		// Numbers and points in time can only be held by text.
+		return "text", true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,147 @@
+package postgres
+
+import (
+	"database/sql"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/atb-as/cleos/pkg/cleos/s1"
+)
+
+func TestMigration(t *testing.T) {
+	table := []TableColumn{
+		{Name: "orderid", Type: "text"},
+		{Name: "quantity", Type: "int"},
+		{Name: "amountinclvat", Type: "numeric(19,2)"},
+		{Name: "salesdate", Type: "date"},
+		{Name: "code", Type: "int"},
+		{Name: "removed", Type: "text"},
+		{Name: "optional", Type: "text", Nullable: true},
+		{Name: "extra", Type: "int"},
+	}
+	columns := []s1.Column{
+		{Name: "OrderId", Type: s1.TypeString},
+		{Name: "Quantity", Type: s1.TypeInteger, Min: 0, Max: math.MaxInt32 + 1},
+		{Name: "AmountInclVat", Type: s1.TypeAmount, Nullable: true},
+		{Name: "SalesDate", Type: s1.TypeDateTime},
+		{Name: "Code", Type: s1.TypeDate},
+		{Name: "VatAmount", Type: s1.TypeAmount},
+		// Empty on every row of this report.
+		{Name: "Extra", Type: s1.TypeUnknown, Nullable: true},
+	}
+
+	got, err := Migration("sales", table, columns)
+	if err != nil {
+		t.Fatalf("err=%v", err)
+	}
+	want := []string{
+		`ALTER TABLE "sales" ALTER COLUMN "quantity" TYPE bigint;`,
+		`ALTER TABLE "sales" ALTER COLUMN "amountinclvat" DROP NOT NULL;`,
+		`ALTER TABLE "sales" ALTER COLUMN "salesdate" TYPE timestamptz;`,
+		`ALTER TABLE "sales" ALTER COLUMN "code" TYPE text;`,
+		`ALTER TABLE "sales" ADD COLUMN "vatamount" numeric(19,2);`,
+		`ALTER TABLE "sales" ALTER COLUMN "extra" DROP NOT NULL;`,
+		`ALTER TABLE "sales" ADD COLUMN "cleos_source_file" text;`,
+		`ALTER TABLE "sales" ADD COLUMN "cleos_report_id" text;`,
+		`ALTER TABLE "sales" ADD COLUMN "cleos_line" int;`,
+		`ALTER TABLE "sales" ADD COLUMN "cleos_loaded_at" timestamptz;`,
+		`ALTER TABLE "sales" ALTER COLUMN "removed" DROP NOT NULL;`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS "sales_provenance_key" ON "sales" ("cleos_report_id","cleos_source_file","cleos_line");`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestMigrationUpToDate(t *testing.T) {
+	table := []TableColumn{
+		{Name: "quantity", Type: "bigint"},
+		{Name: "vatrate", Type: "numeric", Nullable: true},
+		{Name: "salesdatetime", Type: "timestamptz"},
+		{Name: "cleos_source_file", Type: "text"},
+		{Name: "cleos_report_id", Type: "text"},
+		{Name: "cleos_line", Type: "int"},
+		{Name: "cleos_loaded_at", Type: "timestamptz"},
+	}
+	columns := []s1.Column{
+		{Name: "Quantity", Type: s1.TypeInteger, Min: 0, Max: 2},
+		{Name: "VatRate", Type: s1.TypeDecimal},
+		{Name: "SalesDateTime", Type: s1.TypeDate},
+	}
+
+	got, err := Migration("sales", table, columns)
+	if err != nil {
+		t.Fatalf("err=%v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("got %q, want no statements", got)
+	}
+}
+
+func TestWidenType(t *testing.T) {
+	types := []struct {
+		have, want string
+		wider      string
+		ok         bool
+	}{
+		{"int", "bigint", "bigint", true},
+		{"smallint", "int", "int", true},
+		{"bigint", "numeric", "numeric", true},
+		{"int", "numeric(19,2)", "numeric(19,2)", true},
+		{"numeric(19,2)", "numeric", "numeric", true},
+		{"numeric", "numeric(19,2)", "", false},
+		{"date", "timestamptz", "timestamptz", true},
+		{"timestamptz", "date", "", false},
+		{"int", "date", "text", true},
+		{"date", "text", "text", true},
+		{"text", "int", "", false},
+		{"time without time zone", "timestamptz", "", false},
+	}
+
+	for _, tt := range types {
+		wider, ok := widenType(tt.have, tt.want)
+		if ok != tt.ok || ok && wider != tt.wider {
+			t.Errorf("widenType(%q, %q): got %q, %v, want %q, %v", tt.have, tt.want, wider, ok, tt.wider, tt.ok)
+		}
+	}
+}
+
+func TestTypeName(t *testing.T) {
+	// Columns created by Schema are named the same way when introspected.
+	for _, col := range []s1.Column{
+		{Type: s1.TypeAmount},
+		{Type: s1.TypeDecimal},
+		{Type: s1.TypeDate},
+		{Type: s1.TypeDateTime},
+		{Type: s1.TypeInteger},
+		{Type: s1.TypeInteger, Max: math.MaxInt64},
+		{Type: s1.TypeString},
+	} {
+		typ, err := columnType(col)
+		if err != nil {
+			t.Fatalf("err=%v", err)
+		}
+		dataType, precision, scale := informationSchemaType(typ)
+		if got := typeName(dataType, precision, scale); got != typ {
+			t.Errorf("got %q, want %q", got, typ)
+		}
+	}
+}
+
+// informationSchemaType returns the information_schema description of the
+// column type typ, as created by Schema.
+func informationSchemaType(typ string) (string, sql.NullInt64, sql.NullInt64) {
+	switch typ {
+	case "int":
+		return "integer", sql.NullInt64{Int64: 32, Valid: true}, sql.NullInt64{Valid: true}
+	case "bigint":
+		return "bigint", sql.NullInt64{Int64: 64, Valid: true}, sql.NullInt64{Valid: true}
+	case "numeric(19,2)":
+		return "numeric", sql.NullInt64{Int64: 19, Valid: true}, sql.NullInt64{Int64: 2, Valid: true}
+	case "timestamptz":
+		return "timestamp with time zone", sql.NullInt64{}, sql.NullInt64{}
+	default:
+		return typ, sql.NullInt64{}, sql.NullInt64{}
+	}
+}
@@ -0,0 +1,121 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/atb-as/cleos/pkg/cleos/s1"
+)
+
+// Provenance columns are added to every table created by Schema, and record
+// where each row was loaded from.
+const (
+	SourceFileColumn = "cleos_source_file"
+	ReportIDColumn   = "cleos_report_id"
+	LineColumn       = "cleos_line"
+	LoadedAtColumn   = "cleos_loaded_at"
+)
+
+// provenanceColumns are the provenance columns, in the order Schema adds them
+// and Provenance.Values returns their values.
+var provenanceColumns = []s1.Column{
+	{Name: SourceFileColumn, Type: s1.TypeString},
+	{Name: ReportIDColumn, Type: s1.TypeString},
+	{Name: LineColumn, Type: s1.TypeInteger},
+	{Name: LoadedAtColumn, Type: s1.TypeTimestamp},
+}
+
+// ProvenanceKey are the columns of the unique key Schema creates. A row
+// loaded twice from the same line of the same report file violates the key.
+var ProvenanceKey = []string{ReportIDColumn, SourceFileColumn, LineColumn}
+
+// Provenance records where the rows of an S-1 report are loaded from.
+type Provenance struct {
+	// SourceFile is the name of the file the report is read from, without
+	// any directories.
+	SourceFile string
+	// ReportID is the CLEOS report ID of the report, or "" if unknown.
+	ReportID string
+	LoadedAt time.Time
+}
+
+// ProvenanceColumns returns the names of the provenance columns, in the
+// order Provenance.Values returns their values.
+func ProvenanceColumns() []string {
+	names := make([]string, len(provenanceColumns))
+	for i, col := range provenanceColumns {
+		names[i] = col.Name
+	}
+	return names
+}
+
+// Values returns the values of the provenance columns for the row at line.
+func (p Provenance) Values(line int) []interface{} {
+	return []interface{}{p.SourceFile, p.ReportID, line, p.LoadedAt}
+}
+
+// ConflictAction is what to do with rows that have already been loaded.
+type ConflictAction int
+
+const (
+	// ConflictSkip keeps rows already loaded as they are.
+	ConflictSkip ConflictAction = iota
+	// ConflictUpdate replaces rows already loaded.
+	ConflictUpdate
+)
+
+// OnConflict returns the ON CONFLICT clause of a statement inserting columns,
+// which include the provenance columns, into a table created by Schema.
+func OnConflict(columns []string, action ConflictAction) string {
+	key := make([]string, len(ProvenanceKey))
+	for i, name := range ProvenanceKey {
+		key[i] = ColumnName(name)
+	}
+	clause := fmt.Sprintf("ON CONFLICT (%s) DO ", strings.Join(key, ","))
+	if action == ConflictSkip {
+		return clause + "NOTHING"
+	}
+
+	var set []string
+	for _, name := range columns {
+		if isKey(name) {
+			continue
+		}
+		set = append(set, fmt.Sprintf("%s = EXCLUDED.%[1]s", ColumnName(name)))
+	}
+	return clause + "UPDATE SET " + strings.Join(set, ", ")
+}
+
+func isProvenance(name string) bool {
+	for _, col := range provenanceColumns {
+		if strings.EqualFold(name, col.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+func isKey(name string) bool {
+	for _, key := range ProvenanceKey {
+		if strings.EqualFold(name, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// provenanceIndex returns a statement creating the unique index over
+// ProvenanceKey for tableName.
+func provenanceIndex(tableName string) string {
+	name := tableName
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	key := make([]string, len(ProvenanceKey))
+	for i, col := range ProvenanceKey {
+		key[i] = ColumnName(col)
+	}
+	return fmt.Sprintf("CREATE UNIQUE INDEX IF NOT EXISTS %s ON %s (%s);",
+		ColumnName(name+"_provenance_key"), TableName(tableName), strings.Join(key, ","))
+}
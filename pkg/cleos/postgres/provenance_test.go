@@ -0,0 +1,21 @@
+package postgres
+
+import "testing"
+
+func TestOnConflict(t *testing.T) {
+	columns := append([]string{"OrderId", "Quantity"}, ProvenanceColumns()...)
+	tests := []struct {
+		action ConflictAction
+		want   string
+	}{
+		{ConflictSkip, `ON CONFLICT ("cleos_report_id","cleos_source_file","cleos_line") DO NOTHING`},
+		{ConflictUpdate, `ON CONFLICT ("cleos_report_id","cleos_source_file","cleos_line") DO UPDATE SET ` +
+			`"orderid" = EXCLUDED."orderid", "quantity" = EXCLUDED."quantity", "cleos_loaded_at" = EXCLUDED."cleos_loaded_at"`},
+	}
+
+	for _, tt := range tests {
+		if got := OnConflict(columns, tt.action); got != tt.want {
+			t.Errorf("action %d: got\n%s\nwant\n%s", tt.action, got, tt.want)
+		}
+	}
+}
@@ -4,38 +4,49 @@ import (
 	"fmt"
 	"math"
 	"strings"
-	"time"
+
+	"github.com/atb-as/cleos/pkg/cleos/s1"
 )
 
-// Schema returns a SQL statement for creating a table for the values contained
-// in data. columns specifies column names. data and columns must have the same
-// length.
-func Schema(columns []string, data []interface{}, tableName string) (string, error) {
-	if len(columns) != len(data) {
-		return "", fmt.Errorf("columns and data data have different lengths")
+// Schema returns SQL statements for creating a table for the columns of an
+// S-1 report, as profiled by s1.Profile. Identifiers are normalised and
+// quoted by TableName and ColumnName.
+//
+// The provenance columns are added after the columns of the report, along
+// with a unique index over ProvenanceKey, so that loading a report twice can
+// be detected with ON CONFLICT.
+//
+// Integer columns are int or bigint depending on the range of their values.
+// Columns without any values are text. Columns are NOT NULL unless the
+// profile found empty values in them, so the profile should cover the whole
+// report.
+func Schema(columns []s1.Column, tableName string) (string, error) {
+	if len(columns) == 0 {
+		return "", fmt.Errorf("no columns")
 	}
 	s := &strings.Builder{}
 
+	all := append(columns[:len(columns):len(columns)], provenanceColumns...)
+
 	writeSchemaHeader(s, tableName)
-	for i, val := range data {
-		colName := columns[i]
-		switch t := val.(type) {
-		case float64:
-			writeFloatColumn(s, colName)
-		case time.Time:
-			writeDateColumn(s, colName)
-		case int:
-			writeIntColumn(s, colName, t)
-		case string:
-			writeStringColumn(s, colName)
-		default:
-			return "", fmt.Errorf("unrecognized type for column %d", i)
+	for i, col := range all {
+		if i < len(columns) && isProvenance(col.Name) {
+			return "", fmt.Errorf("column %d: %s is reserved for provenance", i, col.Name)
+		}
+		typ, err := columnType(col)
+		if err != nil {
+			return "", fmt.Errorf("column %d: %v", i, err)
+		}
+		writeColumn(s, col.Name, typ)
+		if !col.Nullable {
+			writeNotNull(s)
 		}
-		if i != len(data)-1 {
+		if i != len(all)-1 {
 			writeComma(s)
 		}
 	}
 	writeSchemaFooter(s)
+	s.WriteString("\n" + provenanceIndex(tableName))
 
 	return s.String(), nil
 }
@@ -44,29 +55,39 @@ func writeComma(s *strings.Builder) {
 	s.Write([]byte(",\n"))
 }
 
-func writeStringColumn(s *strings.Builder, colName string) {
-	s.WriteString(fmt.Sprintf("%s text", strings.ToLower(colName)))
+func writeNotNull(s *strings.Builder) {
+	s.WriteString(" NOT NULL")
 }
 
-func writeDateColumn(s *strings.Builder, colName string) {
-	// Naive as we are, this is probably good enough for our use case.
-	if strings.Contains(strings.ToLower(colName), "time") {
-		s.WriteString(fmt.Sprintf("%s time", strings.ToLower(colName)))
-		return
-	}
-	s.WriteString(fmt.Sprintf("%s date", strings.ToLower(colName)))
-}
-
-func writeIntColumn(s *strings.Builder, colName string, t int) {
-	if t > math.MaxInt32 {
-		s.WriteString(fmt.Sprintf("%s bigint", strings.ToLower(colName)))
-		return
+// columnType returns the Postgres type of columns holding values of col.
+//
+// DateTime and Timestamp values are points in time decoded with their time
+// zone, so they are stored as timestamptz. Amounts are stored in øre as an
+// int64, which has at most 19 digits.
+func columnType(col s1.Column) (string, error) {
+	switch col.Type {
+	case s1.TypeAmount:
+		return "numeric(19,2)", nil
+	case s1.TypeDecimal:
+		return "numeric", nil
+	case s1.TypeDate:
+		return "date", nil
+	case s1.TypeDateTime, s1.TypeTimestamp:
+		return "timestamptz", nil
+	case s1.TypeInteger:
+		if col.Min < math.MinInt32 || col.Max > math.MaxInt32 {
+			return "bigint", nil
+		}
+		return "int", nil
+	case s1.TypeString, s1.TypeUnknown:
+		return "text", nil
+	default:
+		return "", fmt.Errorf("unrecognized type %s", col.Type)
 	}
-	s.WriteString(fmt.Sprintf("%s int", strings.ToLower(colName)))
 }
 
-func writeFloatColumn(s *strings.Builder, colName string) {
-	s.WriteString(fmt.Sprintf("%s numeric", strings.ToLower(colName)))
+func writeColumn(s *strings.Builder, colName, typ string) {
+	s.WriteString(fmt.Sprintf("%s %s", ColumnName(colName), typ))
 }
 
 func writeSchemaFooter(s *strings.Builder) {
@@ -74,5 +95,5 @@ func writeSchemaFooter(s *strings.Builder) {
 }
 
 func writeSchemaHeader(s *strings.Builder, tableName string) {
-	s.WriteString(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n", tableName))
+	s.WriteString(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n", TableName(tableName)))
 }
@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"math"
+	"testing"
+
+	"github.com/atb-as/cleos/pkg/cleos/s1"
+)
+
+func TestSchema(t *testing.T) {
+	columns := []s1.Column{
+		{Name: "OrderId", Type: s1.TypeString},
+		{Name: "Quantity", Type: s1.TypeInteger, Min: -1, Max: 2},
+		{Name: "Big", Type: s1.TypeInteger, Min: 0, Max: math.MaxInt32 + 1},
+		{Name: "Negative", Type: s1.TypeInteger, Min: math.MinInt32 - 1, Max: 0},
+		{Name: "AmountInclVat", Type: s1.TypeAmount, Nullable: true},
+		{Name: "VatRate", Type: s1.TypeDecimal},
+		{Name: "SalesDate", Type: s1.TypeDate},
+		{Name: "SalesDateTime", Type: s1.TypeDateTime},
+		{Name: "Created", Type: s1.TypeTimestamp, Nullable: true},
+		{Name: "Empty", Type: s1.TypeUnknown, Nullable: true},
+	}
+	got, err := Schema(columns, "reports.Sales")
+	if err != nil {
+		t.Fatalf("err=%v", err)
+	}
+	want := `CREATE TABLE IF NOT EXISTS "reports"."sales" (
+"orderid" text NOT NULL,
+"quantity" int NOT NULL,
+"big" bigint NOT NULL,
+"negative" bigint NOT NULL,
+"amountinclvat" numeric(19,2),
+"vatrate" numeric NOT NULL,
+"salesdate" date NOT NULL,
+"salesdatetime" timestamptz NOT NULL,
+"created" timestamptz,
+"empty" text,
+"cleos_source_file" text NOT NULL,
+"cleos_report_id" text NOT NULL,
+"cleos_line" int NOT NULL,
+"cleos_loaded_at" timestamptz NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "sales_provenance_key" ON "reports"."sales" ("cleos_report_id","cleos_source_file","cleos_line");`
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSchemaProvenanceColumn(t *testing.T) {
+	columns := []s1.Column{{Name: "Cleos_Line", Type: s1.TypeInteger}}
+	if _, err := Schema(columns, "sales"); err == nil {
+		t.Errorf("got no error for report column named like a provenance column")
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	names := []struct {
+		arg  string
+		want string
+	}{
+		{"OrderId", `"orderid"`},
+		{"Sum inkl. mva", `"sum inkl. mva"`},
+		{"Beløp", `"beløp"`},
+		{"Order", `"order"`},
+		{`Say "hi"`, `"say ""hi"""`},
+	}
+
+	for _, tt := range names {
+		if got := ColumnName(tt.arg); got != tt.want {
+			t.Errorf("ColumnName(%q): got %s, want %s", tt.arg, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package s1
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+)
+
+// Amount is an exact amount of money in NOK, counted in øre.
+type Amount int64
+
+// ParseAmount parses an amount formatted with exactly 2 decimals and an
+// optional sign, such as "149.00" or "-149.00" for a refund.
+func ParseAmount(s string) (Amount, error) {
+	if decimals, ok := scanNumber(s); !ok || decimals != 2 {
+		return 0, fmt.Errorf("s1: invalid amount %q", s)
+	}
+
+	var neg bool
+	digits := s
+	switch s[0] {
+	case '-':
+		neg = true
+		fallthrough
+	case '+':
+		digits = s[1:]
+	}
+
+	// Accumulate the magnitude in a uint64 so the most negative amount can be
+	// represented.
+	limit := uint64(math.MaxInt64)
+	if neg {
+		limit++
+	}
+	var u uint64
+	for i := 0; i < len(digits); i++ {
+		if digits[i] == '.' {
+			continue
+		}
+		d := uint64(digits[i] - '0')
+		if u > (limit-d)/10 {
+			return 0, fmt.Errorf("s1: amount %q out of range", s)
+		}
+		u = u*10 + d
+	}
+
+	if neg {
+		return Amount(-u), nil
+	}
+	return Amount(u), nil
+}
+
+// String formats a as kroner with 2 decimals, the same way amounts are
+// formatted in S-1 reports.
+func (a Amount) String() string {
+	var sign string
+	u := uint64(a)
+	if a < 0 {
+		sign = "-"
+		u = uint64(-a)
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, u/100, u%100)
+}
+
+// Add returns a+b.
+func (a Amount) Add(b Amount) Amount { return a + b }
+
+// Sub returns a-b.
+func (a Amount) Sub(b Amount) Amount { return a - b }
+
+// Mul returns a multiplied by n, e.g. a unit price by a quantity.
+func (a Amount) Mul(n int64) Amount { return a * Amount(n) }
+
+// Neg returns -a.
+func (a Amount) Neg() Amount { return -a }
+
+// Float64 returns a in kroner as a float64. The result is not exact, and
+// should only be used for presentation.
+func (a Amount) Float64() float64 { return float64(a) / 100 }
+
+// Value implements driver.Valuer. The amount is passed to the database as its
+// decimal string representation, so it is stored exactly in numeric columns.
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
@@ -0,0 +1,79 @@
+package s1
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseAmount(t *testing.T) {
+	amounts := []struct {
+		arg  string
+		want Amount
+	}{
+		{"0.00", 0},
+		{"0.01", 1},
+		{"149.00", 14900},
+		{"99.99", 9999},
+		{"0042.10", 4210},
+		{"92233720368547758.07", math.MaxInt64},
+		{"-149.00", -14900},
+		{"+149.00", 14900},
+		{"-0.00", 0},
+		{"-0.05", -5},
+		{"-92233720368547758.08", math.MinInt64},
+	}
+
+	for _, tt := range amounts {
+		got, err := ParseAmount(tt.arg)
+		if err != nil {
+			t.Errorf("ParseAmount(%q): err=%v", tt.arg, err)
+		}
+		if got != tt.want {
+			t.Errorf("ParseAmount(%q): got %d, want %d", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestParseAmountInvalid(t *testing.T) {
+	invalid := []string{"", "149", "149.0", "149.000", ".00", "1,00", "1.0a", "92233720368547758.08",
+		"-", "-.00", "--1.00", "- 1.00", "1.00-", "1e2.00", "-92233720368547758.09"}
+
+	for _, arg := range invalid {
+		if _, err := ParseAmount(arg); err == nil {
+			t.Errorf("ParseAmount(%q): expected error", arg)
+		}
+	}
+}
+
+func TestAmountString(t *testing.T) {
+	amounts := []struct {
+		arg  Amount
+		want string
+	}{
+		{0, "0.00"},
+		{1, "0.01"},
+		{14900, "149.00"},
+		{-14900, "-149.00"},
+		{-5, "-0.05"},
+		{math.MinInt64, "-92233720368547758.08"},
+	}
+
+	for _, tt := range amounts {
+		if got := tt.arg.String(); got != tt.want {
+			t.Errorf("got %s, want %s", got, tt.want)
+		}
+	}
+}
+
+func TestAmountArithmetic(t *testing.T) {
+	// 0.1 + 0.2 is the classic example of float64 drift.
+	if got := Amount(10).Add(20); got.String() != "0.30" {
+		t.Errorf("got %s, want 0.30", got)
+	}
+	if got := Amount(14900).Mul(3).Sub(100); got != 44600 {
+		t.Errorf("got %s, want 446.00", got)
+	}
+	if got := Amount(14900).Neg(); got != -14900 {
+		t.Errorf("got %s, want -149.00", got)
+	}
+}
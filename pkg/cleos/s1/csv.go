@@ -2,31 +2,117 @@ package s1
 
 import (
 	"encoding/csv"
+	"errors"
+	"fmt"
 	"io"
-	"regexp"
+	"reflect"
 	"strconv"
 	"time"
+	// Embed the time zone database, since DefaultLocation must be available
+	// wherever reports are read.
+	_ "time/tzdata"
+
+	"golang.org/x/text/encoding"
 )
 
 const (
 	Separator = ';'
 )
 
+// DefaultLocation is the time zone of date and time values in S-1 reports,
+// which are in Norwegian local time.
+var DefaultLocation = mustLoadLocation("Europe/Oslo")
+
+func mustLoadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
 type Reader struct {
+	// Spec specifies the types of the columns in the report. Values in columns
+	// not present in Spec have their type inferred from their contents.
+	Spec Spec
+
+	// Location is the time zone of Date and DateTime values in the report,
+	// which carry no offset. It defaults to DefaultLocation.
+	Location *time.Location
+
+	// UTC normalises DateTime and Timestamp values to UTC. Date values are
+	// calendar dates and are always returned as midnight in Location, so
+	// they stay on the same day.
+	UTC bool
+
+	// Lenient makes Row and Decode skip rows with values that can not be
+	// decoded, instead of returning an error. The skipped rows are reported
+	// by Errors.
+	Lenient bool
+
+	// Sample is the number of rows after which the types of columns not
+	// present in Spec are locked to the types inferred so far, saving the
+	// cost of inferring the type of every value. Values that do not match the
+	// locked type of their column result in a *ParseError, so it should only
+	// be set when the types are known not to change later in the report, e.g.
+	// to DefaultSample. Zero, the default, disables locking.
+	Sample int
+
+	// ReuseRow makes Row return a slice sharing its backing array with the
+	// slice returned by the previous call, for better performance. The
+	// returned slice may then only be used until the next call to Row.
+	ReuseRow bool
+
+	// Encoding is the character encoding of the report, which is transcoded
+	// to UTF-8. If nil, the encoding is detected from the start of the report
+	// by DetectEncoding. A byte order mark overrides Encoding, and is never
+	// part of the first column name.
+	Encoding encoding.Encoding
+
+	errs    []*ParseError
+	record  []string
+	rowBuf  []interface{}
 	hdrRead bool
 	hdr     []string
+	types   []Type
+	sampled int
+	samples []sample
+	fields  map[reflect.Type][]field
+	src     io.Reader
 	csv     *csv.Reader
 }
 
+// DefaultSample is a number of rows suitable for Sample, after which the
+// types of columns not present in the Spec are locked.
+const DefaultSample = 1000
+
 func NewReader(r io.Reader) *Reader {
 	reader := &Reader{
-		csv: csv.NewReader(r),
+		Spec:     Columns,
+		Location: DefaultLocation,
+		src:      r,
 	}
-	reader.csv.Comma = Separator
 
 	return reader
 }
 
+// init prepares the reader for reading the report. It is called on the first
+// read rather than by NewReader, so that fields like Encoding can be set
+// after creating the reader.
+func (r *Reader) init() error {
+	if r.csv != nil {
+		return nil
+	}
+	src, err := decodeUTF8(r.src, r.Encoding)
+	if err != nil {
+		return err
+	}
+	r.csv = csv.NewReader(src)
+	r.csv.Comma = Separator
+
+	return nil
+}
+
 func (r *Reader) Header() ([]string, error) {
 	if !r.hdrRead {
 		return r.readHeader()
@@ -34,81 +120,225 @@ func (r *Reader) Header() ([]string, error) {
 	return r.hdr, nil
 }
 
+// Row reads the next row of the report and decodes each value according to
+// the type of its column. The header is read first if it has not been read
+// already.
+//
+// Values that can not be decoded result in a *ParseError, unless the reader
+// is lenient.
 func (r *Reader) Row() ([]interface{}, error) {
-	row, err := r.csv.Read()
+	if !r.hdrRead {
+		if _, err := r.readHeader(); err != nil {
+			return nil, err
+		}
+	}
+
+	for {
+		row, err := r.row()
+		if perr, ok := err.(*ParseError); ok && r.Lenient {
+			r.errs = append(r.errs, perr)
+			continue
+		}
+		return row, err
+	}
+}
+
+// Errors returns the errors of the rows skipped by a lenient reader so far,
+// in the order they appear in the report.
+func (r *Reader) Errors() []*ParseError {
+	return r.errs
+}
+
+// Line returns the line number in the report of the row most recently
+// returned by Row, or 0 if no rows have been returned. Rows spanning several
+// lines are numbered by the line they start on.
+func (r *Reader) Line() int {
+	if r.record == nil {
+		return 0
+	}
+	line, _ := r.csv.FieldPos(0)
+	return line
+}
+
+// readRecord reads the raw values of the next row. Rows that can not be
+// split into values, such as rows with the wrong number of values or with
+// stray quotes, result in a *ParseError, and reading resumes at the next row.
+func (r *Reader) readRecord() ([]string, error) {
+	r.csv.ReuseRecord = r.ReuseRow
+	record, err := r.csv.Read()
+	if cerr := (*csv.ParseError)(nil); errors.As(err, &cerr) {
+		return nil, &ParseError{Line: cerr.Line, Row: append([]string(nil), record...), Err: cerr.Err}
+	}
 	if err != nil {
 		return nil, err
 	}
+	r.record = record
+	return record, nil
+}
 
-	var ret = make([]interface{}, 0, len(row))
-	for _, v := range row {
-		va, err := value(v)
+func (r *Reader) row() ([]interface{}, error) {
+	record, err := r.readRecord()
+	if err != nil {
+		return nil, err
+	}
+
+	z := zone{in: r.Location, out: r.Location}
+	if r.UTC {
+		z.out = time.UTC
+	}
+
+	var ret []interface{}
+	if r.ReuseRow && cap(r.rowBuf) >= len(record) {
+		ret = r.rowBuf[:0]
+	} else {
+		ret = make([]interface{}, 0, len(record))
+	}
+	for i, v := range record {
+		t := r.types[i]
+		if t == TypeUnknown && v != "" {
+			t = r.infer(i, v)
+		}
+		va, err := decode(t, v, z)
 		if err != nil {
-			return nil, err
+			return nil, r.parseError(i, err)
 		}
 		ret = append(ret, va)
 	}
+	r.rowBuf = ret
 
+	if r.sampled++; r.sampled == r.Sample {
+		r.lock()
+	}
 	return ret, nil
 }
 
-// value converts a string value present in a column in an S-1 report to its
-// corresponding Go value.
-//
-// Types in the S-1 report as documented in
-// "M09-12 Generation of Sales Documentation and General Ledger Data":
+// parseError returns a *ParseError for err, caused by the value in column i
+// of the most recently read row.
+func (r *Reader) parseError(i int, err error) *ParseError {
+	line, _ := r.csv.FieldPos(i)
+	return &ParseError{
+		Line:   line,
+		Column: r.hdr[i],
+		Value:  r.record[i],
+		Row:    append([]string(nil), r.record...),
+		Err:    err,
+	}
+}
+
+// zone determines the time zones of decoded date and time values.
+type zone struct {
+	// in is the location of values without an offset.
+	in *time.Location
+	// out is the location DateTime and Timestamp values are returned in.
+	out *time.Location
+}
+
+// decode converts a string value present in a column of type t in an S-1
+// report to its corresponding Go value. Values in columns of TypeUnknown are
+// converted by value.
 //
-// Date: A calendar date without time: dd.MM.yyyy
-// DateTime: A calendar date with a time component: dd.MM.yyyy HH:mm:ss
-// Timestamp: An unrestricted point in time, typically a time of creation. Not
-// seen in the wild yet, so not included.
-// Integer: A number with no decimals.
-// Amount: A number always formatted with 2 decimals. NOK currency.
-// Decimal: A number with at least one decimal.
-// String: A quoted ("") value. Go's encoding/csv package trims the quotes for
-// us automatically.
-func value(v string) (interface{}, error) {
-	amount := regexp.MustCompile("^\\d+\\.\\d{2}$")
-	date := regexp.MustCompile("^\\d{2}\\.\\d{2}\\.\\d{4}$")
-	dateTime := regexp.MustCompile("^\\d{2}\\.\\d{2}\\.\\d{4}\\s+\\d{2}:\\d{2}:\\d{2}$")
-	decimal := regexp.MustCompile("^\\d+\\.\\d+$")
-	integer := regexp.MustCompile("^\\d+$")
-
-	switch {
-	case date.MatchString(v):
-		return decodeDate(v)
-	case dateTime.MatchString(v):
-		return decodeDateTime(v)
-	case integer.MatchString(v):
+// Strings are quoted in the report, but Go's encoding/csv package trims the
+// quotes for us automatically. Empty values in columns of any other type are
+// decoded as Null.
+func decode(t Type, v string, z zone) (interface{}, error) {
+	if v == "" && t != TypeString {
+		return Null{Type: t}, nil
+	}
+
+	switch t {
+	case TypeDate:
+		return decodeDate(v, z)
+	case TypeDateTime:
+		return decodeDateTime(v, z)
+	case TypeTimestamp:
+		return decodeTimestamp(v, z)
+	case TypeInteger:
 		return decodeInt(v)
-	case amount.MatchString(v):
-		fallthrough
-	case decimal.MatchString(v):
+	case TypeAmount:
+		return ParseAmount(v)
+	case TypeDecimal:
 		return decodeDecimal(v)
-	default:
+	case TypeString:
 		return v, nil
+	default:
+		return value(v, z)
 	}
 }
 
+// value converts a string value present in a column in an S-1 report to its
+// corresponding Go value, inferring its type from its contents.
+func value(v string, z zone) (interface{}, error) {
+	return decode(infer(v), v, z)
+}
+
+// decodeDecimal parses a number with an optional sign and decimals. Unlike
+// strconv.ParseFloat it does not accept exponents, infinities or NaN.
 func decodeDecimal(v string) (float64, error) {
+	if _, ok := scanNumber(v); !ok {
+		return 0, fmt.Errorf("s1: invalid decimal %q", v)
+	}
 	return strconv.ParseFloat(v, 64)
 }
 
 func decodeInt(v string) (int, error) {
+	if decimals, ok := scanNumber(v); !ok || decimals != 0 {
+		return 0, fmt.Errorf("s1: invalid integer %q", v)
+	}
 	return strconv.Atoi(v)
 }
 
-func decodeDateTime(v string) (time.Time, error) {
+// decodeDateTime parses v in z.in. DateTime values carry no offset, so during
+// the hour repeated when daylight saving time ends the first occurrence, in
+// summer time, is chosen. Times skipped when daylight saving time starts are
+// moved forward by the length of the gap.
+//
+// Dates are decoded as dates, at midnight, since Profile widens columns with
+// both dates and date times to DateTime.
+func decodeDateTime(v string, z zone) (time.Time, error) {
+	if dateRe.MatchString(v) {
+		return decodeDate(v, z)
+	}
 	const layout = "02.01.2006 15:04:05"
-	return time.Parse(layout, v)
+	t, err := time.ParseInLocation(layout, v, z.in)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return firstOccurrence(t).In(z.out), nil
 }
 
-func decodeDate(v string) (time.Time, error) {
+// firstOccurrence returns the earliest instant with the same wall clock as t
+// in t's location. time.ParseInLocation does not guarantee which instant it
+// returns for a wall clock that occurs twice.
+func firstOccurrence(t time.Time) time.Time {
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+
+	// Any earlier occurrence must use the offset in effect before the most
+	// recent transition, which is at most a day away in practice.
+	_, offset := t.Add(-24 * time.Hour).Zone()
+	u := wall.Add(-time.Duration(offset) * time.Second).In(t.Location())
+	if u.Before(t) && u.Hour() == t.Hour() && u.Minute() == t.Minute() && u.Day() == t.Day() {
+		return u
+	}
+	return t
+}
+
+func decodeDate(v string, z zone) (time.Time, error) {
 	const layout = "02.01.2006"
-	return time.Parse(layout, v)
+	return time.ParseInLocation(layout, v, z.in)
+}
+
+func decodeTimestamp(v string, z zone) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.In(z.out), nil
 }
 
 func (r *Reader) readHeader() ([]string, error) {
+	if err := r.init(); err != nil {
+		return nil, err
+	}
 	hdr, err := r.csv.Read()
 	if err != nil {
 		return nil, err
@@ -116,5 +346,11 @@ func (r *Reader) readHeader() ([]string, error) {
 	r.hdr = hdr
 	r.hdrRead = true
 
+	r.types = make([]Type, len(hdr))
+	r.samples = make([]sample, len(hdr))
+	for i, name := range hdr {
+		r.types[i] = r.Spec.Type(name)
+	}
+
 	return hdr, nil
 }
@@ -1,14 +1,20 @@
 package s1
 
 import (
+	"encoding/csv"
+	"fmt"
+	"io"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
 
+var utc = zone{in: time.UTC, out: time.UTC}
+
 func TestValueDate(t *testing.T) {
 	date := "22.09.2020"
-	got, err := value(date)
+	got, err := value(date, utc)
 	if err != nil {
 		t.Errorf("err=%v", err)
 	}
@@ -21,7 +27,7 @@ func TestValueDate(t *testing.T) {
 
 func TestValueDateTime(t *testing.T) {
 	dateTime := "22.09.2020 10:00:00"
-	got, err := value(dateTime)
+	got, err := value(dateTime, utc)
 	if err != nil {
 		t.Errorf("err=%v", err)
 	}
@@ -47,7 +53,7 @@ func TestValueInteger(t *testing.T) {
 	}
 
 	for _, tt := range integers {
-		got, err := value(tt.arg)
+		got, err := value(tt.arg, utc)
 		if err != nil {
 			t.Errorf("err=%v", err)
 		}
@@ -69,7 +75,7 @@ func TestValueDecimal(t *testing.T) {
 	}
 
 	for _, tt := range decimals {
-		got, err := value(tt.arg)
+		got, err := value(tt.arg, utc)
 		if err != nil {
 			t.Errorf("err=%v", err)
 		}
@@ -83,7 +89,7 @@ func TestValueString(t *testing.T) {
 	strings := []string{"hello", "world"}
 
 	for i, tt := range strings {
-		got, err := value(tt)
+		got, err := value(tt, utc)
 		if err != nil {
 			t.Errorf("err=%v", err)
 		}
@@ -93,3 +99,483 @@ func TestValueString(t *testing.T) {
 		}
 	}
 }
+
+func TestReaderSpec(t *testing.T) {
+	const report = `AccountingDate;ProductCode;Quantity;AmountInclVat;Unspecified
+22.09.2020;"0042";2;149.00;"0042"
+`
+	r := NewReader(strings.NewReader(report))
+	got, err := r.Row()
+	if err != nil {
+		t.Fatalf("err=%v", err)
+	}
+	want := []interface{}{time.Date(2020, 9, 22, 0, 0, 0, 0, DefaultLocation), "0042", 2, Amount(14900), 42}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
+
+func TestValueSigned(t *testing.T) {
+	values := []struct {
+		arg  string
+		want interface{}
+	}{
+		{"-1", -1},
+		{"+1", 1},
+		{"-0", 0},
+		{"-0042", -42},
+		{"-149.00", -149.00},
+		{"+0.5", 0.5},
+		{"-0.001", -0.001},
+	}
+
+	for _, tt := range values {
+		got, err := value(tt.arg, utc)
+		if err != nil {
+			t.Errorf("value(%q): err=%v", tt.arg, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("value(%q): got %#v, want %#v", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestValueNotNumber(t *testing.T) {
+	strings := []string{"-", "+", "1e5", "NaN", "Inf", ".5", "5.", "1.2.3", "1 000", "--1", "-1-"}
+
+	for _, tt := range strings {
+		got, err := value(tt, utc)
+		if err != nil {
+			t.Errorf("value(%q): err=%v", tt, err)
+		}
+		if got != tt {
+			t.Errorf("value(%q): got %#v, want %#v", tt, got, tt)
+		}
+	}
+}
+
+func TestDecodeNumberInvalid(t *testing.T) {
+	values := []struct {
+		typ Type
+		arg string
+	}{
+		{TypeInteger, "1.0"},
+		{TypeInteger, "1e5"},
+		{TypeInteger, "-"},
+		{TypeDecimal, "NaN"},
+		{TypeDecimal, "1e5"},
+		{TypeDecimal, "-.5"},
+		{TypeAmount, "-149"},
+		{TypeAmount, "-149.0"},
+	}
+
+	for _, tt := range values {
+		if got, err := decode(tt.typ, tt.arg, utc); err == nil {
+			t.Errorf("decode(%s, %q): got %#v, expected error", tt.typ, tt.arg, got)
+		}
+	}
+}
+
+func TestReaderRefund(t *testing.T) {
+	const report = `SalesDate;OrderId;Quantity;AmountExclVat;VatAmount;AmountInclVat;VatRate
+22.09.2020;"A1";1;133.04;15.96;149.00;12.0
+22.09.2020;"A1";-1;-133.04;-15.96;-149.00;12.0
+`
+	r := NewReader(strings.NewReader(report))
+
+	var sum Amount
+	var quantity int
+	for {
+		row, err := r.Row()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("err=%v", err)
+		}
+		quantity += row[2].(int)
+		sum = sum.Add(row[5].(Amount))
+		if got, want := row[3].(Amount).Add(row[4].(Amount)), row[5].(Amount); got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	}
+
+	if quantity != 0 {
+		t.Errorf("got quantity %d, want 0", quantity)
+	}
+	if sum != 0 {
+		t.Errorf("got sum %s, want 0.00", sum)
+	}
+}
+
+func TestReaderLocation(t *testing.T) {
+	const report = `AccountingDate;SalesDateTime
+22.09.2020;22.09.2020 23:30:00
+`
+	r := NewReader(strings.NewReader(report))
+	got, err := r.Row()
+	if err != nil {
+		t.Fatalf("err=%v", err)
+	}
+	want := []interface{}{
+		time.Date(2020, 9, 22, 0, 0, 0, 0, DefaultLocation),
+		time.Date(2020, 9, 22, 23, 30, 0, 0, DefaultLocation),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if sale := got[1].(time.Time); !sale.Equal(time.Date(2020, 9, 22, 21, 30, 0, 0, time.UTC)) {
+		t.Errorf("got %v, want 21:30 UTC", sale.UTC())
+	}
+}
+
+func TestReaderUTC(t *testing.T) {
+	const report = `AccountingDate;SalesDateTime
+22.09.2020;22.09.2020 00:30:00
+`
+	r := NewReader(strings.NewReader(report))
+	r.UTC = true
+	got, err := r.Row()
+	if err != nil {
+		t.Fatalf("err=%v", err)
+	}
+	want := []interface{}{
+		// Dates are not normalised, as that would move them to the day before.
+		time.Date(2020, 9, 22, 0, 0, 0, 0, DefaultLocation),
+		time.Date(2020, 9, 21, 22, 30, 0, 0, time.UTC),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestDecodeDateTimeDST(t *testing.T) {
+	oslo := zone{in: DefaultLocation, out: time.UTC}
+	dateTimes := []struct {
+		arg  string
+		want time.Time
+	}{
+		// Last hour of winter time and first hour of summer time.
+		{"29.03.2020 01:30:00", time.Date(2020, 3, 29, 0, 30, 0, 0, time.UTC)},
+		{"29.03.2020 03:30:00", time.Date(2020, 3, 29, 1, 30, 0, 0, time.UTC)},
+		// Skipped when the clocks move forward.
+		{"29.03.2020 02:30:00", time.Date(2020, 3, 29, 1, 30, 0, 0, time.UTC)},
+		// Repeated when the clocks move back, resolved to summer time.
+		{"25.10.2020 02:30:00", time.Date(2020, 10, 25, 0, 30, 0, 0, time.UTC)},
+		{"25.10.2020 03:30:00", time.Date(2020, 10, 25, 2, 30, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range dateTimes {
+		got, err := decodeDateTime(tt.arg, oslo)
+		if err != nil {
+			t.Errorf("err=%v", err)
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("decodeDateTime(%q): got %v, want %v", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeDateTimeDate(t *testing.T) {
+	oslo := zone{in: DefaultLocation, out: time.UTC}
+	got, err := decodeDateTime("22.09.2020", oslo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2020, 9, 22, 0, 0, 0, 0, DefaultLocation)
+	if !got.Equal(want) || got.Location() != DefaultLocation {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestReaderSpecDateTime(t *testing.T) {
+	const report = `Start
+22.09.2020 00:30:00
+23.09.2020
+`
+	r := NewReader(strings.NewReader(report))
+	r.Spec = Spec{"Start": TypeDateTime}
+	r.UTC = true
+	var got []interface{}
+	for {
+		row, err := r.Row()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, row[0])
+	}
+	want := []interface{}{
+		time.Date(2020, 9, 21, 22, 30, 0, 0, time.UTC),
+		time.Date(2020, 9, 23, 0, 0, 0, 0, DefaultLocation),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestReaderNull(t *testing.T) {
+	const report = `AccountingDate;ProductCode;Quantity;AmountInclVat;Unspecified
+;"";;;
+`
+	r := NewReader(strings.NewReader(report))
+	got, err := r.Row()
+	if err != nil {
+		t.Fatalf("err=%v", err)
+	}
+	want := []interface{}{Null{TypeDate}, "", Null{TypeInteger}, Null{TypeAmount}, Null{TypeUnknown}}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
+
+func TestReaderParseError(t *testing.T) {
+	const report = `OrderId;Quantity;AmountInclVat
+"A1";1;149.00
+"multi
+line";2;abc
+`
+	r := NewReader(strings.NewReader(report))
+	if _, err := r.Row(); err != nil {
+		t.Fatalf("err=%v", err)
+	}
+
+	_, err := r.Row()
+	perr, ok := err.(*ParseError)
+	if !ok {
+		t.Fatalf("got %#v, want *ParseError", err)
+	}
+	if perr.Line != 4 || perr.Column != "AmountInclVat" || perr.Value != "abc" {
+		t.Errorf("got line %d, column %q, value %q, want line 4, column %q, value %q",
+			perr.Line, perr.Column, perr.Value, "AmountInclVat", "abc")
+	}
+	if want := `s1: line 4, column "AmountInclVat": s1: invalid amount "abc"`; perr.Error() != want {
+		t.Errorf("got %q, want %q", perr.Error(), want)
+	}
+}
+
+func TestReaderLenient(t *testing.T) {
+	const report = `OrderId;Quantity;AmountInclVat
+"A1";1;149.00
+"A2";x;149.00
+"A3";1
+"A4";1;-149.00
+"A"5";1;1.00
+"A6";2;2.00
+`
+	r := NewReader(strings.NewReader(report))
+	r.Lenient = true
+
+	var orders []string
+	for {
+		row, err := r.Row()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("err=%v", err)
+		}
+		orders = append(orders, row[0].(string))
+	}
+	if want := []string{"A1", "A4", "A6"}; !reflect.DeepEqual(orders, want) {
+		t.Errorf("got %v, want %v", orders, want)
+	}
+
+	errs := r.Errors()
+	if len(errs) != 3 {
+		t.Fatalf("got %d errors, want 3", len(errs))
+	}
+	if errs[0].Line != 3 || errs[0].Column != "Quantity" || errs[0].Value != "x" {
+		t.Errorf("got %+v", errs[0])
+	}
+	if errs[1].Line != 4 || errs[1].Column != "" || !reflect.DeepEqual(errs[1].Row, []string{"A3", "1"}) {
+		t.Errorf("got %+v", errs[1])
+	}
+	if errs[2].Line != 6 || errs[2].Err != csv.ErrQuote {
+		t.Errorf("got %+v", errs[2])
+	}
+}
+
+func TestReaderLine(t *testing.T) {
+	const report = `OrderId;ProductName;Quantity
+"A1";"Single";1
+"A2";"Single";x
+"A3";"Multi
+line";1
+"A4";"Single";1
+`
+	r := NewReader(strings.NewReader(report))
+	r.Spec = Spec{"ProductName": TypeString, "Quantity": TypeInteger}
+	r.Lenient = true
+	if got := r.Line(); got != 0 {
+		t.Errorf("before first row: got line %d, want 0", got)
+	}
+
+	var lines []int
+	for {
+		if _, err := r.Row(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("err=%v", err)
+		}
+		lines = append(lines, r.Line())
+	}
+	if want := []int{2, 4, 6}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("got lines %v, want %v", lines, want)
+	}
+}
+
+// benchmarkReport returns an S-1 report with n rows, where every other column
+// is not in Columns and has its type inferred.
+func benchmarkReport(n int) string {
+	var b strings.Builder
+	b.WriteString("AccountingDate;SalesDateTime;OrderId;ProductCode;Quantity;AmountInclVat;VatRate;" +
+		"ExtraDate;ExtraDateTime;ExtraCode;ExtraInteger;ExtraAmount;ExtraDecimal\n")
+	for i := 0; i < n; i++ {
+		b.WriteString(`22.09.2020;22.09.2020 23:30:00;"A1";"0042";1;149.00;12.0;`)
+		b.WriteString(`22.09.2020;22.09.2020 23:30:00;"B42";2;-149.00;0.125` + "\n")
+	}
+	return b.String()
+}
+
+func benchmarkReader(b *testing.B, setup func(r *Reader)) {
+	const rows = 10000
+	report := benchmarkReport(rows)
+	b.SetBytes(int64(len(report)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		r := NewReader(strings.NewReader(report))
+		setup(r)
+		for {
+			_, err := r.Row()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				b.Fatalf("err=%v", err)
+			}
+		}
+	}
+	b.ReportMetric(float64(b.N*rows)/time.Since(start).Seconds(), "rows/s")
+}
+
+func BenchmarkReader(b *testing.B) {
+	benchmarkReader(b, func(r *Reader) {})
+}
+
+func BenchmarkReaderReuseRow(b *testing.B) {
+	benchmarkReader(b, func(r *Reader) { r.ReuseRow = true })
+}
+
+func BenchmarkReaderSample(b *testing.B) {
+	benchmarkReader(b, func(r *Reader) { r.Sample = DefaultSample })
+}
+
+func TestReaderSample(t *testing.T) {
+	const report = `Integer;Decimal;Mixed;Empty
+1;1;01.01.2020;
+2;2.5;01.01.2020 10:00:00;
+3;3;"3";
+x;4;"4";01.01.2020
+`
+	r := NewReader(strings.NewReader(report))
+	r.Sample = 3
+
+	var rows [][]interface{}
+	for i := 0; i < 3; i++ {
+		row, err := r.Row()
+		if err != nil {
+			t.Fatalf("err=%v", err)
+		}
+		rows = append(rows, row)
+	}
+	want := []interface{}{3, 3, 3, Null{TypeUnknown}}
+	if !reflect.DeepEqual(rows[2], want) {
+		t.Errorf("got %#v, want %#v", rows[2], want)
+	}
+
+	// Integer is locked to Integer, Decimal to Decimal, Mixed to String and
+	// Empty remains unlocked.
+	_, err := r.Row()
+	if perr, ok := err.(*ParseError); !ok || perr.Column != "Integer" {
+		t.Fatalf("got %v, want *ParseError in column Integer", err)
+	}
+	if got, want := r.samples[1], (sample{TypeDecimal, true}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got, want := r.samples[2], (sample{TypeString, true}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got, want := r.samples[3], (sample{}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestReaderTypeChangeAfterSample(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("Extra\n")
+	for i := 0; i < DefaultSample; i++ {
+		fmt.Fprintf(&b, "%d\n", i)
+	}
+	b.WriteString("\"abc\"\n")
+
+	// Types are not locked by default, so the value after the sample is
+	// still decoded.
+	r := NewReader(strings.NewReader(b.String()))
+	var last []interface{}
+	for {
+		row, err := r.Row()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("err=%v", err)
+		}
+		last = row
+	}
+	if want := []interface{}{"abc"}; !reflect.DeepEqual(last, want) {
+		t.Errorf("got %#v, want %#v", last, want)
+	}
+
+	r = NewReader(strings.NewReader(b.String()))
+	r.Sample = DefaultSample
+	for {
+		_, err := r.Row()
+		if perr, ok := err.(*ParseError); ok {
+			if perr.Line != DefaultSample+2 || perr.Value != "abc" {
+				t.Errorf("got %v, want error for abc on line %d", perr, DefaultSample+2)
+			}
+			break
+		}
+		if err != nil {
+			t.Fatalf("got %v, want *ParseError", err)
+		}
+	}
+}
+
+func TestReaderReuseRow(t *testing.T) {
+	r := NewReader(strings.NewReader("Quantity\n1\n2\n"))
+	r.ReuseRow = true
+
+	first, err := r.Row()
+	if err != nil {
+		t.Fatalf("err=%v", err)
+	}
+	second, err := r.Row()
+	if err != nil {
+		t.Fatalf("err=%v", err)
+	}
+	if &first[0] != &second[0] {
+		t.Errorf("expected rows to share backing array")
+	}
+	if second[0] != 2 {
+		t.Errorf("got %v, want 2", second[0])
+	}
+}
@@ -0,0 +1,78 @@
+package s1
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// detectSize is the number of bytes inspected when detecting the encoding of
+// a report.
+const detectSize = 64 * 1024
+
+// ErrInvalidUTF8 is returned when reading a report detected to be UTF-8 that
+// is not valid UTF-8 beyond the start inspected by DetectEncoding.
+var ErrInvalidUTF8 = errors.New("s1: invalid UTF-8 after the start of a report detected as UTF-8, set the encoding of the report")
+
+// decodeUTF8 returns a reader transcoding src from enc to UTF-8, stripping any
+// byte order mark. A byte order mark overrides enc. If enc is nil, it is
+// detected by DetectEncoding.
+//
+// Only the start of the report is inspected when detecting the encoding, so
+// a report detected as UTF-8 is validated as it is read rather than having
+// invalid characters replaced, which would silently corrupt them.
+func decodeUTF8(src io.Reader, enc encoding.Encoding) (io.Reader, error) {
+	br := bufio.NewReaderSize(src, detectSize)
+	if enc == nil {
+		b, err := br.Peek(detectSize)
+		if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+			return nil, err
+		}
+		if enc = DetectEncoding(b); enc == unicode.UTF8 {
+			t := transform.Chain(encoding.UTF8Validator, unicode.BOMOverride(transform.Nop))
+			return &validUTF8Reader{transform.NewReader(br, t)}, nil
+		}
+	}
+	return transform.NewReader(br, unicode.BOMOverride(enc.NewDecoder())), nil
+}
+
+// validUTF8Reader reports the invalid UTF-8 found by encoding.UTF8Validator
+// as ErrInvalidUTF8.
+type validUTF8Reader struct {
+	r io.Reader
+}
+
+func (r *validUTF8Reader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err == encoding.ErrInvalidUTF8 {
+		err = ErrInvalidUTF8
+	}
+	return n, err
+}
+
+// DetectEncoding returns the encoding of a report starting with b. Reports
+// are either UTF-8 or, when b is not valid UTF-8, Windows-1252 as produced by
+// some CLEOS exports. A byte order mark in b is ignored, as it is handled
+// when decoding.
+func DetectEncoding(b []byte) encoding.Encoding {
+	// b may end in the middle of a multi-byte character.
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				b = b[:i]
+			}
+			break
+		}
+	}
+
+	if utf8.Valid(b) {
+		return unicode.UTF8
+	}
+	return charmap.Windows1252
+}
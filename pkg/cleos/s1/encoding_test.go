@@ -0,0 +1,106 @@
+package s1
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func TestReaderBOM(t *testing.T) {
+	const report = "\xef\xbb\xbfOrderId;FareProductName\n\"A1\";\"Enkeltbillett Ålesund\"\n"
+	r := NewReader(strings.NewReader(report))
+
+	hdr, err := r.Header()
+	if err != nil {
+		t.Fatalf("err=%v", err)
+	}
+	if want := []string{"OrderId", "FareProductName"}; !reflect.DeepEqual(hdr, want) {
+		t.Errorf("got %q, want %q", hdr, want)
+	}
+	row, err := r.Row()
+	if err != nil {
+		t.Fatalf("err=%v", err)
+	}
+	if want := []interface{}{"A1", "Enkeltbillett Ålesund"}; !reflect.DeepEqual(row, want) {
+		t.Errorf("got %q, want %q", row, want)
+	}
+}
+
+func TestReaderWindows1252(t *testing.T) {
+	const report = "OrderId;FareProductName\n\"A1\";\"Øvre Årdal – Bæ\"\n"
+	encoded, err := charmap.Windows1252.NewEncoder().String(report)
+	if err != nil {
+		t.Fatalf("err=%v", err)
+	}
+
+	for _, tt := range []struct {
+		name  string
+		setup func(r *Reader)
+	}{
+		{"detected", func(r *Reader) {}},
+		{"configured", func(r *Reader) { r.Encoding = charmap.Windows1252 }},
+	} {
+		r := NewReader(strings.NewReader(encoded))
+		tt.setup(r)
+		row, err := r.Row()
+		if err != nil {
+			t.Fatalf("%s: err=%v", tt.name, err)
+		}
+		if want := []interface{}{"A1", "Øvre Årdal – Bæ"}; !reflect.DeepEqual(row, want) {
+			t.Errorf("%s: got %q, want %q", tt.name, row, want)
+		}
+	}
+}
+
+func TestDetectEncoding(t *testing.T) {
+	// A valid UTF-8 prefix cut in the middle of "Å".
+	utf8 := append(bytes.Repeat([]byte("a"), 10), "Å"[0])
+	if got := DetectEncoding(utf8); got != unicode.UTF8 {
+		t.Errorf("got %v, want UTF-8", got)
+	}
+	if got := DetectEncoding([]byte("\xc5lesund")); got != charmap.Windows1252 {
+		t.Errorf("got %v, want Windows-1252", got)
+	}
+}
+
+func TestReaderInvalidUTF8AfterDetection(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("OrderId;FareProductName\n")
+	for b.Len() < detectSize {
+		b.WriteString("\"A1\";\"Enkeltbillett\"\n")
+	}
+	b.WriteString("\"A2\";\"Bl\xe5\"\n")
+
+	r := NewReader(strings.NewReader(b.String()))
+	for {
+		_, err := r.Row()
+		if err == ErrInvalidUTF8 {
+			break
+		}
+		if err != nil {
+			t.Fatalf("got err=%v, want %v", err, ErrInvalidUTF8)
+		}
+	}
+
+	r = NewReader(strings.NewReader(b.String()))
+	r.Encoding = charmap.Windows1252
+	var last []interface{}
+	for {
+		row, err := r.Row()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("err=%v", err)
+		}
+		last = row
+	}
+	if want := []interface{}{"A2", "Blå"}; !reflect.DeepEqual(last, want) {
+		t.Errorf("got %q, want %q", last, want)
+	}
+}
@@ -0,0 +1,22 @@
+package s1
+
+import "fmt"
+
+// A ParseError is returned for rows that can not be decoded. It carries the
+// position and raw contents of the offending value.
+type ParseError struct {
+	Line   int      // Line the value is on, starting at 1
+	Column string   // Name of the column, empty if the row could not be split
+	Value  string   // Raw value
+	Row    []string // Raw values of the entire row
+	Err    error    // The underlying error
+}
+
+func (e *ParseError) Error() string {
+	if e.Column == "" {
+		return fmt.Sprintf("s1: line %d: %v", e.Line, e.Err)
+	}
+	return fmt.Sprintf("s1: line %d, column %q: %v", e.Line, e.Column, e.Err)
+}
+
+func (e *ParseError) Unwrap() error { return e.Err }
@@ -0,0 +1,87 @@
+package s1
+
+import (
+	"regexp"
+	"strconv"
+)
+
+var (
+	dateRe     = regexp.MustCompile(`^\d{2}\.\d{2}\.\d{4}$`)
+	dateTimeRe = regexp.MustCompile(`^\d{2}\.\d{2}\.\d{4}\s+\d{2}:\d{2}:\d{2}$`)
+)
+
+// infer returns the type of v as inferred from its contents. Timestamps are
+// never inferred, and TypeUnknown is returned for empty values.
+func infer(v string) Type {
+	if v == "" {
+		return TypeUnknown
+	}
+	if decimals, ok := scanNumber(v); ok {
+		// Integers too large for an int are decoded as Decimal instead.
+		if _, err := strconv.Atoi(v); decimals == 0 && err == nil {
+			return TypeInteger
+		}
+		return TypeDecimal
+	}
+
+	switch {
+	case dateRe.MatchString(v):
+		return TypeDate
+	case dateTimeRe.MatchString(v):
+		return TypeDateTime
+	default:
+		return TypeString
+	}
+}
+
+// sample holds the type inferred for a column not present in the Spec, and
+// whether it has been locked.
+type sample struct {
+	typ    Type
+	locked bool
+}
+
+// widen returns the narrowest type able to hold values of both a and b.
+// Integer and Decimal values are held by Decimal, Date and DateTime values by
+// DateTime, while any other mix of types is held by String.
+func widen(a, b Type) Type {
+	switch {
+	case a == b, b == TypeUnknown:
+		return a
+	case a == TypeUnknown:
+		return b
+	case a == TypeInteger && b == TypeDecimal, a == TypeDecimal && b == TypeInteger:
+		return TypeDecimal
+	case a == TypeDate && b == TypeDateTime, a == TypeDateTime && b == TypeDate:
+		return TypeDateTime
+	default:
+		return TypeString
+	}
+}
+
+// infer returns the type of value v in column i, which is not present in the
+// Spec. Until the column is locked, the type is recorded in the column's
+// sample.
+func (r *Reader) infer(i int, v string) Type {
+	s := &r.samples[i]
+	if s.locked {
+		return s.typ
+	}
+
+	t := infer(v)
+	s.typ = widen(s.typ, t)
+	return t
+}
+
+// lock locks the types of columns not present in the Spec to the types
+// inferred from the rows read so far, widened to hold every value seen.
+// Columns without any values are left unlocked.
+func (r *Reader) lock() {
+	for i := range r.samples {
+		s := &r.samples[i]
+		if r.types[i] != TypeUnknown || s.typ == TypeUnknown {
+			continue
+		}
+		s.locked = true
+	}
+}
@@ -0,0 +1,15 @@
+package s1
+
+import "database/sql/driver"
+
+// Null is the value of an empty cell in a column that is not of TypeString.
+// Type is the type of the column, which is TypeUnknown for columns whose type
+// is inferred from their contents.
+type Null struct {
+	Type Type
+}
+
+// Value implements driver.Valuer, so empty cells are stored as SQL NULL.
+func (Null) Value() (driver.Value, error) {
+	return nil, nil
+}
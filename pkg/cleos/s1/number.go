@@ -0,0 +1,43 @@
+package s1
+
+// scanNumber reports whether v is a number as it may appear in an S-1 report,
+// and if so how many decimals it has. The grammar is
+//
+//	number   = [ sign ] digits [ "." digits ]
+//	sign     = "+" | "-"
+//	digits   = digit { digit }
+//
+// Leading zeros are allowed, while exponents, digit grouping and numbers
+// starting or ending with the decimal point are not.
+func scanNumber(v string) (decimals int, ok bool) {
+	i := 0
+	if i < len(v) && (v[i] == '+' || v[i] == '-') {
+		i++
+	}
+	start := i
+	for i < len(v) && isDigit(v[i]) {
+		i++
+	}
+	if i == start {
+		return 0, false
+	}
+	if i == len(v) {
+		return 0, true
+	}
+	if v[i] != '.' {
+		return 0, false
+	}
+	i++
+	start = i
+	for i < len(v) && isDigit(v[i]) {
+		i++
+	}
+	if i == start || i != len(v) {
+		return 0, false
+	}
+	return i - start, true
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
@@ -0,0 +1,111 @@
+package s1
+
+import (
+	"io"
+	"strconv"
+)
+
+// Column describes the values of a column in an S-1 report, as observed by
+// Profile.
+type Column struct {
+	Name string
+	// Type is the type of the column in the Spec, or for columns not in the
+	// Spec the narrowest type able to hold every value. It is TypeUnknown if
+	// the column has no values.
+	Type Type
+	// Nullable reports whether any value is empty. Empty strings are not
+	// considered null.
+	Nullable bool
+	// Min and Max are the smallest and largest integer values in the column.
+	Min, Max int64
+}
+
+// Profile reads up to sample rows from r, or every row if sample is zero or
+// less, and returns a description of each column. Columns not in the Spec of
+// r are widened as needed to hold every value read, e.g. from Integer to
+// Decimal or from Date to DateTime.
+//
+// Values are not decoded, so Profile only fails on rows with the wrong
+// number of values. Such rows are skipped if r is lenient.
+func Profile(r *Reader, sample int) ([]Column, error) {
+	hdr, err := r.Header()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make([]Column, len(hdr))
+	for i, name := range hdr {
+		columns[i] = Column{Name: name, Type: r.types[i]}
+	}
+	// integers counts the integers seen in each column, to tell whether Min
+	// and Max have been set.
+	integers := make([]int, len(hdr))
+
+	for n := 0; sample <= 0 || n < sample; n++ {
+		record, err := r.readRecord()
+		if err == io.EOF {
+			break
+		}
+		if perr, ok := err.(*ParseError); ok && r.Lenient {
+			r.errs = append(r.errs, perr)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for i, v := range record {
+			if columns[i].observe(r.types[i], v, integers[i] == 0) {
+				integers[i]++
+			}
+		}
+	}
+
+	return columns, nil
+}
+
+// ProfileSpec returns a Spec with the types of columns, as returned by
+// Profile, so that the values of a report can be read and written with the
+// types of their columns rather than of each value. Columns without values are
+// left out.
+func ProfileSpec(columns []Column) Spec {
+	spec := make(Spec, len(columns))
+	for _, col := range columns {
+		if col.Type != TypeUnknown {
+			spec[col.Name] = col.Type
+		}
+	}
+	return spec
+}
+
+// observe updates c with the value v, in a column of type t in the Spec, and
+// reports whether v is an integer. first is true if no integer has been
+// observed in the column before.
+func (c *Column) observe(t Type, v string, first bool) bool {
+	if v == "" {
+		if t != TypeString {
+			c.Nullable = true
+		}
+		return false
+	}
+
+	if t == TypeUnknown {
+		t = infer(v)
+		c.Type = widen(c.Type, t)
+	}
+	if t != TypeInteger {
+		return false
+	}
+
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return false
+	}
+	if first || n < c.Min {
+		c.Min = n
+	}
+	if first || n > c.Max {
+		c.Max = n
+	}
+	return true
+}
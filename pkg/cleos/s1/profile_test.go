@@ -0,0 +1,47 @@
+package s1
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestProfile(t *testing.T) {
+	const report = `AccountingDate;OrderId;Quantity;AmountInclVat;Small;Big;Number;When;Code;Empty
+;"";1;149.00;0;1;1;01.01.2020;1;
+22.09.2020;"A2";2;;5;3000000000;1.5;01.01.2020 10:00:00;"x";
+`
+	got, err := Profile(NewReader(strings.NewReader(report)), 0)
+	if err != nil {
+		t.Fatalf("err=%v", err)
+	}
+	want := []Column{
+		{Name: "AccountingDate", Type: TypeDate, Nullable: true},
+		{Name: "OrderId", Type: TypeString},
+		{Name: "Quantity", Type: TypeInteger, Min: 1, Max: 2},
+		{Name: "AmountInclVat", Type: TypeAmount, Nullable: true},
+		{Name: "Small", Type: TypeInteger, Min: 0, Max: 5},
+		{Name: "Big", Type: TypeInteger, Min: 1, Max: 3000000000},
+		{Name: "Number", Type: TypeDecimal, Min: 1, Max: 1},
+		{Name: "When", Type: TypeDateTime},
+		{Name: "Code", Type: TypeString, Min: 1, Max: 1},
+		{Name: "Empty", Type: TypeUnknown, Nullable: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestProfileSample(t *testing.T) {
+	const report = `Number
+1
+1.5
+`
+	got, err := Profile(NewReader(strings.NewReader(report)), 1)
+	if err != nil {
+		t.Fatalf("err=%v", err)
+	}
+	if want := []Column{{Name: "Number", Type: TypeInteger, Min: 1, Max: 1}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
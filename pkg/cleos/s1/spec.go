@@ -0,0 +1,89 @@
+package s1
+
+// Type is the type of a column in an S-1 report as documented in
+// "M09-12 Generation of Sales Documentation and General Ledger Data".
+type Type int
+
+const (
+	// TypeUnknown is the type of columns not present in a Spec. Values in such
+	// columns have their type inferred from their contents.
+	TypeUnknown Type = iota
+	// TypeDate is a calendar date without time: dd.MM.yyyy
+	TypeDate
+	// TypeDateTime is a calendar date with a time component: dd.MM.yyyy HH:mm:ss
+	TypeDateTime
+	// TypeTimestamp is an unrestricted point in time, typically a time of
+	// creation. Not seen in the wild yet, so it is assumed to be RFC 3339.
+	TypeTimestamp
+	// TypeInteger is a number with no decimals.
+	TypeInteger
+	// TypeAmount is a number always formatted with 2 decimals. NOK currency.
+	TypeAmount
+	// TypeDecimal is a number with at least one decimal.
+	TypeDecimal
+	// TypeString is a quoted ("") value.
+	TypeString
+)
+
+func (t Type) String() string {
+	switch t {
+	case TypeDate:
+		return "Date"
+	case TypeDateTime:
+		return "DateTime"
+	case TypeTimestamp:
+		return "Timestamp"
+	case TypeInteger:
+		return "Integer"
+	case TypeAmount:
+		return "Amount"
+	case TypeDecimal:
+		return "Decimal"
+	case TypeString:
+		return "String"
+	default:
+		return "Unknown"
+	}
+}
+
+// Spec maps column names in an S-1 report to their type.
+type Spec map[string]Type
+
+// Columns is the specification of the columns in the standard S-1 sales
+// report.
+var Columns = Spec{
+	"AccountingDate":        TypeDate,
+	"SalesDate":             TypeDate,
+	"SalesDateTime":         TypeDateTime,
+	"TravelDate":            TypeDate,
+	"OrganisationId":        TypeInteger,
+	"OrganisationName":      TypeString,
+	"DistributionChannelId": TypeString,
+	"PointOfSaleId":         TypeString,
+	"OrderId":               TypeString,
+	"OrderVersion":          TypeInteger,
+	"OrderLineId":           TypeString,
+	"TransactionId":         TypeString,
+	"ProductCode":           TypeString,
+	"FareProductId":         TypeString,
+	"FareProductName":       TypeString,
+	"UserProfileName":       TypeString,
+	"ZoneFrom":              TypeString,
+	"ZoneTo":                TypeString,
+	"Quantity":              TypeInteger,
+	"AmountExclVat":         TypeAmount,
+	"VatAmount":             TypeAmount,
+	"AmountInclVat":         TypeAmount,
+	"VatRate":               TypeDecimal,
+	"Currency":              TypeString,
+	"PaymentMethod":         TypeString,
+}
+
+// Type returns the type of the column named name, or TypeUnknown if the
+// column is not part of s.
+func (s Spec) Type(name string) Type {
+	if t, ok := s[name]; ok {
+		return t
+	}
+	return TypeUnknown
+}
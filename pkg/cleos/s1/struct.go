@@ -0,0 +1,182 @@
+package s1
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// SalesRecord holds a row of the standard S-1 sales report as described by
+// Columns.
+type SalesRecord struct {
+	AccountingDate        time.Time `s1:"AccountingDate"`
+	SalesDate             time.Time `s1:"SalesDate"`
+	SalesDateTime         time.Time `s1:"SalesDateTime"`
+	TravelDate            time.Time `s1:"TravelDate"`
+	OrganisationID        int       `s1:"OrganisationId"`
+	OrganisationName      string    `s1:"OrganisationName"`
+	DistributionChannelID string    `s1:"DistributionChannelId"`
+	PointOfSaleID         string    `s1:"PointOfSaleId"`
+	OrderID               string    `s1:"OrderId"`
+	OrderVersion          int       `s1:"OrderVersion"`
+	OrderLineID           string    `s1:"OrderLineId"`
+	TransactionID         string    `s1:"TransactionId"`
+	ProductCode           string    `s1:"ProductCode"`
+	FareProductID         string    `s1:"FareProductId"`
+	FareProductName       string    `s1:"FareProductName"`
+	UserProfileName       string    `s1:"UserProfileName"`
+	ZoneFrom              string    `s1:"ZoneFrom"`
+	ZoneTo                string    `s1:"ZoneTo"`
+	Quantity              int       `s1:"Quantity"`
+	AmountExclVat         Amount    `s1:"AmountExclVat"`
+	VatAmount             Amount    `s1:"VatAmount"`
+	AmountInclVat         Amount    `s1:"AmountInclVat"`
+	VatRate               float64   `s1:"VatRate"`
+	Currency              string    `s1:"Currency"`
+	PaymentMethod         string    `s1:"PaymentMethod"`
+}
+
+// field maps a struct field to the column in the report it is decoded from.
+type field struct {
+	index  int
+	column int
+}
+
+// Decode reads the next row of the report and stores it in the struct pointed
+// to by v. Struct fields are matched against the header using the column name
+// in their s1 tag, e.g. `s1:"AccountingDate"`. Fields without a tag are left
+// untouched.
+//
+// Fields may be pointers, in which case empty cells are decoded as nil. Empty
+// cells are decoded as the zero value in fields that are not pointers.
+//
+// Decode returns an error if a tagged column is not present in the header, or
+// if a tagged field is not exported.
+// Values that can not be decoded or stored in their field result in a
+// *ParseError, unless the reader is lenient. At the end of the report Decode
+// returns io.EOF.
+func (r *Reader) Decode(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("s1: Decode requires a non-nil pointer to a struct, got %T", v)
+	}
+	rv = rv.Elem()
+
+	fields, err := r.structFields(rv.Type())
+	if err != nil {
+		return err
+	}
+
+	for {
+		row, err := r.Row()
+		if err != nil {
+			return err
+		}
+
+		err = r.store(rv, fields, row)
+		if perr, ok := err.(*ParseError); ok && r.Lenient {
+			r.errs = append(r.errs, perr)
+			continue
+		}
+		return err
+	}
+}
+
+// store stores the values in row in the fields of rv.
+func (r *Reader) store(rv reflect.Value, fields []field, row []interface{}) error {
+	for _, f := range fields {
+		if err := set(rv.Field(f.index), row[f.column]); err != nil {
+			return r.parseError(f.column, fmt.Errorf("field %s: %v", rv.Type().Field(f.index).Name, err))
+		}
+	}
+	return nil
+}
+
+// structFields returns the fields of t that are tagged with a column name,
+// matched against the header of the report.
+func (r *Reader) structFields(t reflect.Type) ([]field, error) {
+	if fields, ok := r.fields[t]; ok {
+		return fields, nil
+	}
+
+	hdr, err := r.Header()
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int, len(hdr))
+	for i, name := range hdr {
+		columns[name] = i
+	}
+
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		name, ok := t.Field(i).Tag.Lookup("s1")
+		if !ok || name == "-" {
+			continue
+		}
+		if t.Field(i).PkgPath != "" {
+			return nil, fmt.Errorf("s1: field %s of column %q is not exported", t.Field(i).Name, name)
+		}
+		col, ok := columns[name]
+		if !ok {
+			return nil, fmt.Errorf("s1: column %q of field %s not present in header", name, t.Field(i).Name)
+		}
+		fields = append(fields, field{index: i, column: col})
+	}
+
+	if r.fields == nil {
+		r.fields = make(map[reflect.Type][]field)
+	}
+	r.fields[t] = fields
+	return fields, nil
+}
+
+// set stores the decoded value val in dst, converting between numeric types of
+// the same kind when the value fits. Integers are also stored in floating
+// point fields, since values without decimals in columns not present in the
+// Spec are decoded as integers.
+func set(dst reflect.Value, val interface{}) error {
+	if _, ok := val.(Null); ok {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+	if dst.Kind() == reflect.Ptr {
+		elem := reflect.New(dst.Type().Elem())
+		if err := set(elem.Elem(), val); err != nil {
+			return err
+		}
+		dst.Set(elem)
+		return nil
+	}
+
+	src := reflect.ValueOf(val)
+	if src.Type().AssignableTo(dst.Type()) {
+		dst.Set(src)
+		return nil
+	}
+
+	switch src.Kind() {
+	case reflect.Int:
+		switch dst.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if dst.OverflowInt(src.Int()) {
+				return fmt.Errorf("value %d overflows %s", src.Int(), dst.Type())
+			}
+			dst.SetInt(src.Int())
+			return nil
+		case reflect.Float32, reflect.Float64:
+			dst.SetFloat(float64(src.Int()))
+			return nil
+		}
+	case reflect.Float64:
+		switch dst.Kind() {
+		case reflect.Float32, reflect.Float64:
+			if dst.OverflowFloat(src.Float()) {
+				return fmt.Errorf("value %v overflows %s", src.Float(), dst.Type())
+			}
+			dst.SetFloat(src.Float())
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot store %T in %s", val, dst.Type())
+}
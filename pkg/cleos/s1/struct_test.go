@@ -0,0 +1,121 @@
+package s1
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecode(t *testing.T) {
+	const report = `SalesDate;ProductCode;Quantity;AmountInclVat;Unspecified
+22.09.2020;"0042";2;149.00;"hello"
+23.09.2020;"0043";-;;
+`
+	type record struct {
+		SalesDate     time.Time `s1:"SalesDate"`
+		ProductCode   string    `s1:"ProductCode"`
+		Quantity      int16     `s1:"Quantity"`
+		AmountInclVat Amount    `s1:"AmountInclVat"`
+		Ignored       string
+	}
+
+	r := NewReader(strings.NewReader(report))
+	var got record
+	if err := r.Decode(&got); err != nil {
+		t.Fatalf("err=%v", err)
+	}
+	want := record{
+		SalesDate:     time.Date(2020, 9, 22, 0, 0, 0, 0, DefaultLocation),
+		ProductCode:   "0042",
+		Quantity:      2,
+		AmountInclVat: 14900,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	if err := r.Decode(&got); err == nil {
+		t.Errorf("expected error decoding invalid integer")
+	}
+}
+
+func TestDecodeEOF(t *testing.T) {
+	r := NewReader(strings.NewReader("ProductCode\n"))
+	var rec struct {
+		ProductCode string `s1:"ProductCode"`
+	}
+	if err := r.Decode(&rec); err != io.EOF {
+		t.Errorf("got %v, want %v", err, io.EOF)
+	}
+}
+
+func TestDecodeMissingColumn(t *testing.T) {
+	r := NewReader(strings.NewReader("ProductCode\n\"0042\"\n"))
+	var rec SalesRecord
+	if err := r.Decode(&rec); err == nil {
+		t.Errorf("expected error for missing column")
+	}
+}
+
+func TestDecodeTypeMismatch(t *testing.T) {
+	r := NewReader(strings.NewReader("ProductCode\n\"0042\"\n"))
+	var rec struct {
+		ProductCode int `s1:"ProductCode"`
+	}
+	if err := r.Decode(&rec); err == nil {
+		t.Errorf("expected error for type mismatch")
+	}
+}
+
+func TestDecodeNull(t *testing.T) {
+	const report = `SalesDate;Quantity;AmountInclVat
+;;149.00
+`
+	var got struct {
+		SalesDate     *time.Time `s1:"SalesDate"`
+		Quantity      int        `s1:"Quantity"`
+		AmountInclVat *Amount    `s1:"AmountInclVat"`
+	}
+	got.Quantity = 1
+
+	r := NewReader(strings.NewReader(report))
+	if err := r.Decode(&got); err != nil {
+		t.Fatalf("err=%v", err)
+	}
+	if got.SalesDate != nil {
+		t.Errorf("got SalesDate %v, want nil", got.SalesDate)
+	}
+	if got.Quantity != 0 {
+		t.Errorf("got Quantity %d, want 0", got.Quantity)
+	}
+	if got.AmountInclVat == nil || *got.AmountInclVat != 14900 {
+		t.Errorf("got AmountInclVat %v, want 149.00", got.AmountInclVat)
+	}
+}
+
+func TestDecodeUnexported(t *testing.T) {
+	r := NewReader(strings.NewReader("Quantity\n2\n"))
+	var rec struct {
+		q int `s1:"Quantity"`
+	}
+	if err := r.Decode(&rec); err == nil || !strings.Contains(err.Error(), "not exported") {
+		t.Errorf("got err=%v, want error for unexported field", err)
+	}
+}
+
+func TestDecodeIntToFloat(t *testing.T) {
+	r := NewReader(strings.NewReader("Distance\n12\n12.5\n"))
+	var rec struct {
+		Distance float64 `s1:"Distance"`
+	}
+	for _, want := range []float64{12, 12.5} {
+		if err := r.Decode(&rec); err != nil {
+			t.Fatalf("err=%v", err)
+		}
+		if rec.Distance != want {
+			t.Errorf("got Distance %v, want %v", rec.Distance, want)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package s1
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Writer writes rows in the S-1 report format. Values are formatted the way
+// they are in reports generated by CLEOS, so rows read by a Reader can be
+// written back in the same format.
+type Writer struct {
+	// Spec specifies the types of the columns in the report. It decides
+	// whether time.Time values in a column are written as dates or date times.
+	// Values in columns not present in Spec are written as date times, so
+	// reports with other columns than Columns should be written with the
+	// ProfileSpec of the report they were read from.
+	Spec Spec
+
+	// Location is the time zone DateTime values are written in. It defaults
+	// to DefaultLocation.
+	Location *time.Location
+
+	// UseCRLF terminates lines with \r\n instead of \n.
+	UseCRLF bool
+
+	hdr   []string
+	types []Type
+	w     *bufio.Writer
+}
+
+// NewWriter returns a new Writer that writes to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{
+		Spec:     Columns,
+		Location: DefaultLocation,
+		w:        bufio.NewWriter(w),
+	}
+}
+
+// WriteHeader writes the header of the report. It must be called before the
+// first call to Write, and the order of its columns is the order of the
+// values in every row.
+func (w *Writer) WriteHeader(hdr []string) error {
+	if w.hdr != nil {
+		return fmt.Errorf("s1: header already written")
+	}
+	w.hdr = hdr
+	w.types = make([]Type, len(hdr))
+	for i, name := range hdr {
+		w.types[i] = w.Spec.Type(name)
+	}
+
+	for i, name := range hdr {
+		if i > 0 {
+			w.w.WriteRune(Separator)
+		}
+		w.w.WriteString(name)
+	}
+	return w.writeLineEnd()
+}
+
+// Write writes a single row. Values are formatted according to the type of
+// their column:
+//
+//	string    quoted, with embedded quotes doubled
+//	time.Time dd.MM.yyyy, dd.MM.yyyy HH:mm:ss in Location, or RFC 3339 in
+//	          TypeTimestamp columns
+//	Amount    2 decimals
+//	float64   at least 1 decimal, or exactly 2 in TypeAmount columns
+//	int       no decimals
+//	Null, nil empty
+//
+// Writes are buffered, so Flush must be called to ensure the row has been
+// written to the underlying io.Writer.
+func (w *Writer) Write(row []interface{}) error {
+	if w.hdr == nil {
+		return fmt.Errorf("s1: header not written")
+	}
+	if len(row) != len(w.hdr) {
+		return fmt.Errorf("s1: row has %d values, header has %d columns", len(row), len(w.hdr))
+	}
+
+	for i, v := range row {
+		if i > 0 {
+			w.w.WriteRune(Separator)
+		}
+		s, err := w.format(w.types[i], v)
+		if err != nil {
+			return fmt.Errorf("s1: column %q: %v", w.hdr[i], err)
+		}
+		w.w.WriteString(s)
+	}
+	return w.writeLineEnd()
+}
+
+// Flush writes any buffered data to the underlying io.Writer.
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
+
+func (w *Writer) writeLineEnd() error {
+	var err error
+	if w.UseCRLF {
+		_, err = w.w.WriteString("\r\n")
+	} else {
+		err = w.w.WriteByte('\n')
+	}
+	return err
+}
+
+func (w *Writer) format(t Type, v interface{}) (string, error) {
+	switch v := v.(type) {
+	case nil, Null:
+		return "", nil
+	case string:
+		return `"` + strings.ReplaceAll(v, `"`, `""`) + `"`, nil
+	case Amount:
+		return v.String(), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		if t == TypeAmount {
+			return strconv.FormatFloat(v, 'f', 2, 64), nil
+		}
+		s := strconv.FormatFloat(v, 'f', -1, 64)
+		if !strings.Contains(s, ".") {
+			s += ".0"
+		}
+		return s, nil
+	case time.Time:
+		return w.formatTime(t, v), nil
+	default:
+		return "", fmt.Errorf("unsupported type %T", v)
+	}
+}
+
+// formatTime formats v according to t. Dates are calendar dates and are
+// written as is, while date times are converted to Location.
+func (w *Writer) formatTime(t Type, v time.Time) string {
+	switch t {
+	case TypeTimestamp:
+		return v.Format(time.RFC3339Nano)
+	case TypeDate:
+		return v.Format("02.01.2006")
+	default:
+		return v.In(w.Location).Format("02.01.2006 15:04:05")
+	}
+}
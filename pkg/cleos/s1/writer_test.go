@@ -0,0 +1,103 @@
+package s1
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriterRoundTrip(t *testing.T) {
+	const report = `AccountingDate;SalesDateTime;OrderId;ProductCode;Quantity;AmountInclVat;VatRate;Unspecified;Shipped;Empty
+22.09.2020;22.09.2020 23:30:00;"A1";"0042";1;149.00;12.0;"say ""hi""";22.09.2020 00:00:00;
+22.09.2020;22.09.2020 23:45:00;"A2";"";-1;-149.00;12.5;"22.09.2020";25.10.2020 01:00:00;
+`
+	columns, err := Profile(NewReader(strings.NewReader(report)), 0)
+	if err != nil {
+		t.Fatalf("err=%v", err)
+	}
+	spec := ProfileSpec(columns)
+
+	r := NewReader(strings.NewReader(report))
+	r.Spec = spec
+	r.UTC = true
+	hdr, err := r.Header()
+	if err != nil {
+		t.Fatalf("err=%v", err)
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.Spec = spec
+	if err := w.WriteHeader(hdr); err != nil {
+		t.Fatalf("err=%v", err)
+	}
+	for {
+		row, err := r.Row()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("err=%v", err)
+		}
+		if err := w.Write(row); err != nil {
+			t.Fatalf("err=%v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("err=%v", err)
+	}
+
+	if got := buf.String(); got != report {
+		t.Errorf("got\n%s\nwant\n%s", got, report)
+	}
+}
+
+func TestWriterFormat(t *testing.T) {
+	values := []struct {
+		typ  Type
+		arg  interface{}
+		want string
+	}{
+		{TypeString, "Ålesund", `"Ålesund"`},
+		{TypeAmount, Amount(-14900), "-149.00"},
+		{TypeAmount, 149.0, "149.00"},
+		{TypeDecimal, 25.0, "25.0"},
+		{TypeInteger, 42, "42"},
+		{TypeDate, time.Date(2020, 9, 22, 0, 0, 0, 0, time.UTC), "22.09.2020"},
+		{TypeDateTime, time.Date(2020, 9, 22, 21, 30, 0, 0, time.UTC), "22.09.2020 23:30:00"},
+		{TypeTimestamp, time.Date(2020, 9, 22, 21, 30, 0, 0, time.UTC), "2020-09-22T21:30:00Z"},
+		{TypeUnknown, time.Date(2020, 9, 22, 23, 30, 0, 0, DefaultLocation), "22.09.2020 23:30:00"},
+		{TypeUnknown, time.Date(2020, 9, 22, 0, 0, 0, 0, DefaultLocation), "22.09.2020 00:00:00"},
+		{TypeDate, Null{TypeDate}, ""},
+	}
+
+	w := NewWriter(ioutil.Discard)
+	for _, tt := range values {
+		got, err := w.format(tt.typ, tt.arg)
+		if err != nil {
+			t.Errorf("format(%s, %v): err=%v", tt.typ, tt.arg, err)
+		}
+		if got != tt.want {
+			t.Errorf("format(%s, %v): got %q, want %q", tt.typ, tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestWriterInvalid(t *testing.T) {
+	w := NewWriter(ioutil.Discard)
+	if err := w.Write([]interface{}{"A1"}); err == nil {
+		t.Errorf("expected error writing row before header")
+	}
+	if err := w.WriteHeader([]string{"OrderId"}); err != nil {
+		t.Fatalf("err=%v", err)
+	}
+	if err := w.Write([]interface{}{"A1", "A2"}); err == nil {
+		t.Errorf("expected error writing row with too many values")
+	}
+	if err := w.Write([]interface{}{struct{}{}}); err == nil {
+		t.Errorf("expected error writing unsupported type")
+	}
+}